@@ -57,6 +57,14 @@ func main() {
 	webFetchTool := mcpHandler.GetWebFetchTool()
 	s.AddTool(webFetchTool, mcpHandler.HandleWebFetch)
 
+	// Add feed reader tool
+	feedReadTool := mcpHandler.GetFeedReadTool()
+	s.AddTool(feedReadTool, mcpHandler.HandleFeedRead)
+
+	// Add sitemap tool
+	sitemapTool := mcpHandler.GetSitemapTool()
+	s.AddTool(sitemapTool, mcpHandler.HandleSitemap)
+
 	// Add ping tool
 	pingTool := mcpHandler.GetPingTool()
 	s.AddTool(pingTool, mcpHandler.HandlePing)
@@ -67,10 +75,15 @@ func main() {
 	if cfg.BigModel.Token != "" {
 		log.Printf("  - BigModel Token: %s...%s", cfg.BigModel.Token[:8], cfg.BigModel.Token[len(cfg.BigModel.Token)-8:])
 	} else {
-		log.Printf("  - BigModel Token: Not configured (using default)")
+		log.Printf("  - BigModel Token: Not configured (BigModel engines disabled)")
+	}
+	if cfg.SearXNG.BaseURL != "" {
+		log.Printf("  - SearXNG Base URL: %s", cfg.SearXNG.BaseURL)
 	}
+	log.Printf("  - Default Search Engine: %s", cfg.Search.DefaultEngine)
 	log.Printf("  - Web Fetch Timeout: %v", cfg.WebFetch.Timeout)
 	log.Printf("  - User Agent Rotation: %v", cfg.WebFetch.UserAgentRotate)
+	log.Printf("  - Polite Mode (robots.txt): %v", cfg.WebFetch.Polite.Enabled)
 	log.Printf("  - Max Content Size: %d", cfg.WebFetch.MaxContentSize)
 
 	if err := server.ServeStdio(s); err != nil {
@@ -85,10 +98,25 @@ func showUsage() {
 	fmt.Println("  -help             Show this help message")
 	fmt.Println()
 	fmt.Println("Environment Variables:")
-	fmt.Println("  BIGMODEL_TOKEN    BigModel API token (required)")
+	fmt.Println("  BIGMODEL_TOKEN    BigModel API token (required unless only SearXNG is used)")
 	fmt.Println("  BIGMODEL_BASE_URL BigModel API base URL (default: https://open.bigmodel.cn/api/paas/v4/web_search)")
 	fmt.Println("  BIGMODEL_TIMEOUT  BigModel API timeout (default: 30s)")
+	fmt.Println("  SEARXNG_BASE_URL  Base URL of a self-hosted SearXNG instance (enables the searxng engine)")
+	fmt.Println("  SEARCH_DEFAULT_ENGINE Default search engine (default: BIGMODEL_SEARCH_ENGINE)")
 	fmt.Println("  WEBFETCH_TIMEOUT  Web fetch timeout (default: 30s)")
 	fmt.Println("  WEBFETCH_MAX_CONTENT_SIZE Maximum content size to fetch (default: 5000)")
 	fmt.Println("  WEBFETCH_USER_AGENT_ROTATE Enable user agent rotation (default: true)")
+	fmt.Println("  WEBFETCH_MAX_CONCURRENT Maximum fetches in flight across all hosts (default: 16)")
+	fmt.Println("  WEBFETCH_MAX_CONCURRENT_PER_HOST Maximum fetches in flight to one host (default: 2)")
+	fmt.Println("  WEBFETCH_HOST_MIN_INTERVAL Minimum time between requests to the same host (default: 500ms)")
+	fmt.Println("  WEBFETCH_MAX_REDIRECTS Maximum redirects followed per fetch (default: 10)")
+	fmt.Println("  WEBFETCH_ALLOW_CROSS_HOST_REDIRECTS Follow redirects to other hosts (default: true)")
+	fmt.Println("  WEBFETCH_REFUSE_HTTPS_DOWNGRADE Refuse redirects from https to http (default: true)")
+	fmt.Println("  WEBFETCH_SSRF_PROTECTION Block private, loopback and link-local destinations (default: true)")
+	fmt.Println("  WEBFETCH_ALLOWED_HOSTS Trusted internal hosts exempt from SSRF protection")
+	fmt.Println("  WEBFETCH_POLITE_MODE Honour robots.txt and crawl delays with an identifying user agent (default: false)")
+	fmt.Println("  WEBFETCH_ROBOTS_PRODUCT_TOKEN robots.txt user agent token in polite mode (default: ez-web-search)")
+	fmt.Println("  POLICY_RULES      URL policy rules separated by semicolons, e.g. \"deny host:*.example.com\"")
+	fmt.Println("  POLICY_RULES_FILE File with one URL policy rule per line")
+	fmt.Println("  POLICY_DEFAULT_ACTION Action for URLs matching no rule: allow, deny or confirm (default: allow)")
 }
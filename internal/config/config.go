@@ -2,17 +2,26 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"ez-web-search/internal/policy"
 )
 
 // Config holds all configuration for the application
 type Config struct {
 	Server    ServerConfig
 	BigModel  BigModelConfig
+	SearXNG   SearXNGConfig
+	Search    SearchConfig
 	WebFetch  WebFetchConfig
 	UserAgent UserAgentConfig
+	Policy    PolicyConfig
 }
 
 // ServerConfig holds server-specific configuration
@@ -29,6 +38,39 @@ type BigModelConfig struct {
 	SearchEngine string
 }
 
+// SearXNGConfig holds configuration for a self-hosted SearXNG instance
+type SearXNGConfig struct {
+	BaseURL    string
+	Timeout    time.Duration
+	Categories string
+	Language   string
+	SafeSearch int
+}
+
+// SearchConfig holds provider-independent search configuration
+type SearchConfig struct {
+	DefaultEngine    string
+	FusionK          int
+	FusionMaxResults int
+	FailoverEngines  []string
+	Health           ProviderHealthConfig
+	BlockedDomains   []string
+	MinResults       int
+	TopUpAttempts    int
+	CacheTTL         time.Duration
+	CacheSize        int
+	// MaxResponseSize caps the bytes read from a provider response
+	MaxResponseSize int64
+}
+
+// ProviderHealthConfig controls when a failing search provider is taken out of rotation
+type ProviderHealthConfig struct {
+	Window         int
+	MinSamples     int
+	ErrorThreshold float64
+	Cooldown       time.Duration
+}
+
 // WebFetchConfig holds web fetching configuration
 type WebFetchConfig struct {
 	Timeout         time.Duration
@@ -38,6 +80,95 @@ type WebFetchConfig struct {
 	UserAgentRotate bool
 	DelayMin        time.Duration
 	DelayMax        time.Duration
+	Cache           HTTPCacheConfig
+	// DocumentCacheTTL keeps extracted pages for paginated reads
+	DocumentCacheTTL  time.Duration
+	DocumentCacheSize int
+	// MaxBodySize caps the bytes read from a response, both as received and after decompression
+	MaxBodySize int64
+	// AllowPartialBody parses the first MaxBodySize bytes of oversized pages instead of failing
+	AllowPartialBody bool
+	SSRF             SSRFConfig
+	Polite           PoliteConfig
+	Scheduler        SchedulerConfig
+	Redirects        RedirectConfig
+}
+
+// RedirectConfig is the default redirect policy of web fetching
+type RedirectConfig struct {
+	MaxRedirects int
+	// AllowCrossHost follows redirects to a host other than the requested one
+	AllowCrossHost bool
+	// RefuseHTTPSDowngrade refuses redirects from https to http URLs
+	RefuseHTTPSDowngrade bool
+}
+
+// SchedulerConfig limits how many fetches run at once and how often the same host is hit
+type SchedulerConfig struct {
+	// MaxConcurrent caps the requests in flight across all hosts; 0 removes the cap
+	MaxConcurrent int
+	// MaxPerHost caps the requests in flight to a single host; 0 removes the cap
+	MaxPerHost int
+	// MinInterval is the minimum time between the starts of two requests to the same host
+	MinInterval time.Duration
+}
+
+// PoliteConfig controls the opt-in polite mode, which honours robots.txt and identifies the
+// client honestly instead of rotating browser user agents
+type PoliteConfig struct {
+	Enabled   bool
+	UserAgent string
+	// ProductToken selects the robots.txt group that applies, e.g. "User-agent: ez-web-search"
+	ProductToken   string
+	RobotsCacheTTL time.Duration
+	// MaxWait caps how long a request may be held back to honour a crawl delay
+	MaxWait time.Duration
+}
+
+// SSRFConfig controls which network destinations web fetching may connect to
+type SSRFConfig struct {
+	// Enabled blocks private, loopback, link-local, multicast and reserved addresses
+	Enabled bool
+	// BlockedCIDRs are blocked in addition to the built-in ranges
+	BlockedCIDRs []string
+	// AllowedCIDRs and AllowedHosts (exact or *.example.com) are trusted even if internal
+	AllowedCIDRs []string
+	AllowedHosts []string
+}
+
+// HTTPCacheConfig holds the HTTP response cache configuration for web fetching
+type HTTPCacheConfig struct {
+	Enabled      bool
+	Store        string
+	Dir          string
+	MaxEntries   int
+	MaxBytes     int64
+	MaxEntrySize int64
+	// MaxStaleOnError is how stale a cached response may be and still be served when the
+	// origin fails; 0 serves it only where stale-if-error allows
+	MaxStaleOnError time.Duration
+}
+
+// PolicyConfig holds the URL policy enforced on fetched URLs and search result links
+type PolicyConfig struct {
+	// DefaultAction applies to URLs matching no rule: allow, deny or confirm
+	DefaultAction string
+	// Rules are evaluated in order, followed by the rules in RulesFile (one per line)
+	Rules     []string
+	RulesFile string
+}
+
+// LoadRules returns the configured rules followed by those of the rules file
+func (p PolicyConfig) LoadRules() ([]string, error) {
+	rules := append([]string{}, p.Rules...)
+	if p.RulesFile == "" {
+		return rules, nil
+	}
+	data, err := os.ReadFile(p.RulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy rules file: %w", err)
+	}
+	return append(rules, strings.Split(string(data), "\n")...), nil
 }
 
 // UserAgentConfig holds user agent rotation configuration
@@ -47,16 +178,44 @@ type UserAgentConfig struct {
 
 // Load loads configuration from environment variables with defaults
 func Load() *Config {
+	bigModelEngine := getEnv("BIGMODEL_SEARCH_ENGINE", "search_std")
+	serverVersion := getEnv("SERVER_VERSION", "1.0.0")
+
 	return &Config{
 		Server: ServerConfig{
 			Name:    getEnv("SERVER_NAME", "EZ Web Search & Fetch MCP Server"),
-			Version: getEnv("SERVER_VERSION", "1.0.0"),
+			Version: serverVersion,
 		},
 		BigModel: BigModelConfig{
 			Token:        getEnv("BIGMODEL_TOKEN", ""),
 			BaseURL:      getEnv("BIGMODEL_BASE_URL", "https://open.bigmodel.cn/api/paas/v4/web_search"),
 			Timeout:      getDurationEnv("BIGMODEL_TIMEOUT", 30*time.Second),
-			SearchEngine: getEnv("BIGMODEL_SEARCH_ENGINE", "search_std"),
+			SearchEngine: bigModelEngine,
+		},
+		SearXNG: SearXNGConfig{
+			BaseURL:    getEnv("SEARXNG_BASE_URL", ""),
+			Timeout:    getDurationEnv("SEARXNG_TIMEOUT", 30*time.Second),
+			Categories: getEnv("SEARXNG_CATEGORIES", "general"),
+			Language:   getEnv("SEARXNG_LANGUAGE", ""),
+			SafeSearch: getIntEnv("SEARXNG_SAFESEARCH", 0),
+		},
+		Search: SearchConfig{
+			DefaultEngine:    getEnv("SEARCH_DEFAULT_ENGINE", bigModelEngine),
+			FusionK:          getIntEnv("SEARCH_FUSION_K", 60),
+			FusionMaxResults: getIntEnv("SEARCH_FUSION_MAX_RESULTS", 20),
+			FailoverEngines:  getListEnv("SEARCH_FAILOVER_ENGINES", nil),
+			Health: ProviderHealthConfig{
+				Window:         getIntEnv("SEARCH_HEALTH_WINDOW", 20),
+				MinSamples:     getIntEnv("SEARCH_HEALTH_MIN_SAMPLES", 3),
+				ErrorThreshold: getFloatEnv("SEARCH_HEALTH_ERROR_THRESHOLD", 0.5),
+				Cooldown:       getDurationEnv("SEARCH_HEALTH_COOLDOWN", 60*time.Second),
+			},
+			BlockedDomains:  getListEnv("SEARCH_BLOCKED_DOMAINS", nil),
+			MinResults:      getIntEnv("SEARCH_MIN_RESULTS", 5),
+			TopUpAttempts:   getIntEnv("SEARCH_TOP_UP_ATTEMPTS", 2),
+			CacheTTL:        getDurationEnv("SEARCH_CACHE_TTL", 10*time.Minute),
+			CacheSize:       getIntEnv("SEARCH_CACHE_SIZE", 256),
+			MaxResponseSize: int64(getIntEnv("SEARCH_MAX_RESPONSE_SIZE", 5<<20)),
 		},
 		WebFetch: WebFetchConfig{
 			Timeout:         getDurationEnv("WEBFETCH_TIMEOUT", 30*time.Second),
@@ -66,17 +225,87 @@ func Load() *Config {
 			UserAgentRotate: getBoolEnv("WEBFETCH_USER_AGENT_ROTATE", true),
 			DelayMin:        getDurationEnv("WEBFETCH_DELAY_MIN", 1*time.Second),
 			DelayMax:        getDurationEnv("WEBFETCH_DELAY_MAX", 3*time.Second),
+			Cache: HTTPCacheConfig{
+				Enabled:         getBoolEnv("WEBFETCH_CACHE_ENABLED", true),
+				Store:           getEnv("WEBFETCH_CACHE_STORE", "memory"),
+				Dir:             getEnv("WEBFETCH_CACHE_DIR", defaultCacheDir()),
+				MaxEntries:      getIntEnv("WEBFETCH_CACHE_MAX_ENTRIES", 512),
+				MaxBytes:        int64(getIntEnv("WEBFETCH_CACHE_MAX_BYTES", 64<<20)),
+				MaxEntrySize:    int64(getIntEnv("WEBFETCH_CACHE_MAX_ENTRY_SIZE", 8<<20)),
+				MaxStaleOnError: getDurationEnv("WEBFETCH_CACHE_MAX_STALE_ON_ERROR", 0),
+			},
+			DocumentCacheTTL:  getDurationEnv("WEBFETCH_DOCUMENT_CACHE_TTL", 10*time.Minute),
+			DocumentCacheSize: getIntEnv("WEBFETCH_DOCUMENT_CACHE_SIZE", 64),
+			MaxBodySize:       int64(getIntEnv("WEBFETCH_MAX_BODY_SIZE", 10<<20)),
+			AllowPartialBody:  getBoolEnv("WEBFETCH_ALLOW_PARTIAL_BODY", false),
+			SSRF: SSRFConfig{
+				Enabled:      getBoolEnv("WEBFETCH_SSRF_PROTECTION", true),
+				BlockedCIDRs: getListEnv("WEBFETCH_BLOCKED_CIDRS", nil),
+				AllowedCIDRs: getListEnv("WEBFETCH_ALLOWED_CIDRS", nil),
+				AllowedHosts: getListEnv("WEBFETCH_ALLOWED_HOSTS", nil),
+			},
+			Polite: PoliteConfig{
+				Enabled:        getBoolEnv("WEBFETCH_POLITE_MODE", false),
+				UserAgent:      getEnv("WEBFETCH_POLITE_USER_AGENT", "ez-web-search/"+serverVersion+" (+https://github.com/easylearning-vip/ez-web-search)"),
+				ProductToken:   getEnv("WEBFETCH_ROBOTS_PRODUCT_TOKEN", "ez-web-search"),
+				RobotsCacheTTL: getDurationEnv("WEBFETCH_ROBOTS_CACHE_TTL", time.Hour),
+				MaxWait:        getDurationEnv("WEBFETCH_POLITE_MAX_WAIT", time.Minute),
+			},
+			Scheduler: SchedulerConfig{
+				MaxConcurrent: getIntEnv("WEBFETCH_MAX_CONCURRENT", 16),
+				MaxPerHost:    getIntEnv("WEBFETCH_MAX_CONCURRENT_PER_HOST", 2),
+				MinInterval:   getDurationEnv("WEBFETCH_HOST_MIN_INTERVAL", 500*time.Millisecond),
+			},
+			Redirects: RedirectConfig{
+				MaxRedirects:         getIntEnv("WEBFETCH_MAX_REDIRECTS", 10),
+				AllowCrossHost:       getBoolEnv("WEBFETCH_ALLOW_CROSS_HOST_REDIRECTS", true),
+				RefuseHTTPSDowngrade: getBoolEnv("WEBFETCH_REFUSE_HTTPS_DOWNGRADE", true),
+			},
 		},
 		UserAgent: UserAgentConfig{
 			Pool: getDefaultUserAgents(),
 		},
+		Policy: PolicyConfig{
+			DefaultAction: getEnv("POLICY_DEFAULT_ACTION", "allow"),
+			Rules:         getRuleListEnv("POLICY_RULES"),
+			RulesFile:     getEnv("POLICY_RULES_FILE", ""),
+		},
 	}
 }
 
 // Validate validates the configuration and returns an error if invalid
 func (c *Config) Validate() error {
 	if c.BigModel.Token == "" {
-		return fmt.Errorf("BIGMODEL_TOKEN is required and must be set")
+		// Deployments that never talk to BigModel may run on SearXNG alone
+		if c.SearXNG.BaseURL == "" || c.Search.DefaultEngine != "searxng" {
+			return fmt.Errorf("BIGMODEL_TOKEN is required unless SEARXNG_BASE_URL is set and SEARCH_DEFAULT_ENGINE=searxng")
+		}
+	}
+	if threshold := c.Search.Health.ErrorThreshold; threshold <= 0 || threshold > 1 {
+		return fmt.Errorf("SEARCH_HEALTH_ERROR_THRESHOLD must be greater than 0 and at most 1: %g", threshold)
+	}
+	if c.Search.TopUpAttempts < 0 {
+		return fmt.Errorf("SEARCH_TOP_UP_ATTEMPTS must not be negative: %d", c.Search.TopUpAttempts)
+	}
+	for _, cidr := range slices.Concat(c.WebFetch.SSRF.BlockedCIDRs, c.WebFetch.SSRF.AllowedCIDRs) {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			if _, err := netip.ParseAddr(cidr); err != nil {
+				return fmt.Errorf("invalid CIDR range in WEBFETCH_BLOCKED_CIDRS or WEBFETCH_ALLOWED_CIDRS: %q", cidr)
+			}
+		}
+	}
+	if c.WebFetch.Polite.Enabled && strings.Trim(c.WebFetch.Polite.ProductToken, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_-") != "" {
+		return fmt.Errorf("WEBFETCH_ROBOTS_PRODUCT_TOKEN may only contain letters, underscores and hyphens: %q", c.WebFetch.Polite.ProductToken)
+	}
+	if c.WebFetch.Redirects.MaxRedirects < 0 {
+		return fmt.Errorf("WEBFETCH_MAX_REDIRECTS must not be negative: %d", c.WebFetch.Redirects.MaxRedirects)
+	}
+	rules, err := c.Policy.LoadRules()
+	if err != nil {
+		return err
+	}
+	if _, err := policy.New(c.Policy.DefaultAction, rules); err != nil {
+		return fmt.Errorf("invalid URL policy: %w", err)
 	}
 	return nil
 }
@@ -109,6 +338,41 @@ func getBoolEnv(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// getFloatEnv gets a float environment variable with a default value
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getListEnv gets a comma-separated list environment variable with a default value
+func getListEnv(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return defaultValue
+}
+
+// getRuleListEnv gets a semicolon-separated list environment variable; rules may contain commas
+func getRuleListEnv(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // getDurationEnv gets a duration environment variable with a default value
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
@@ -119,6 +383,15 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+// defaultCacheDir returns the per-user cache directory for the disk cache, falling back to a
+// user-specific directory below the temporary directory
+func defaultCacheDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "ez-web-search")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("ez-web-search-cache-%d", os.Getuid()))
+}
+
 // getDefaultUserAgents returns a pool of realistic user agents for rotation
 func getDefaultUserAgents() []string {
 	return []string{
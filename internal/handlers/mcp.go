@@ -3,6 +3,8 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 
@@ -11,19 +13,30 @@ import (
 	"ez-web-search/pkg/types"
 )
 
+// maxFetchLength is the largest chunk a single ez_web_fetch call may return
+const maxFetchLength = 100000
+
+// maxFetchRedirects is the largest max_redirects a single ez_web_fetch call may request
+const maxFetchRedirects = 30
+
 // MCPHandler handles MCP tool requests
 type MCPHandler struct {
 	config           *config.Config
 	webSearchService *services.WebSearchService
 	webFetchService  *services.WebFetchService
+	feedService      *services.FeedService
+	sitemapService   *services.SitemapService
 }
 
 // NewMCPHandler creates a new MCP handler
 func NewMCPHandler(cfg *config.Config) *MCPHandler {
+	webFetchService := services.NewWebFetchService(cfg)
 	return &MCPHandler{
 		config:           cfg,
 		webSearchService: services.NewWebSearchService(cfg),
-		webFetchService:  services.NewWebFetchService(cfg),
+		webFetchService:  webFetchService,
+		feedService:      services.NewFeedService(webFetchService),
+		sitemapService:   services.NewSitemapService(webFetchService),
 	}
 }
 
@@ -36,22 +49,24 @@ func (h *MCPHandler) HandleWebSearch(ctx context.Context, request mcp.CallToolRe
 	}
 
 	// Extract search_engine parameter (optional, defaults to config default)
-	searchEngine := h.config.BigModel.SearchEngine
+	searchEngine := h.config.Search.DefaultEngine
 	if engineVal, exists := request.GetArguments()["search_engine"]; exists {
 		if strVal, ok := engineVal.(string); ok && strVal != "" {
-			// Validate search engine
-			validEngines := map[string]bool{
-				"search_std":       true,
-				"search_pro":       true,
-				"search_pro_sogou": true,
-				"search_pro_quark": true,
-			}
-			if validEngines[strVal] {
+			// Validate search engine against the registered providers
+			if h.webSearchService.HasProvider(strVal) {
 				searchEngine = strVal
 			}
 		}
 	}
 
+	// Extract search_engines parameter (optional, fans out to several engines)
+	searchEngines := getStringSlice(request.GetArguments(), "search_engines")
+	for _, engine := range searchEngines {
+		if engine != "all" && !h.webSearchService.HasProvider(engine) {
+			return mcp.NewToolResultError(fmt.Sprintf("Unknown search engine in search_engines: %s", engine)), nil
+		}
+	}
+
 	// Extract search_intent parameter (optional, defaults to false)
 	searchIntent := false
 	if intentVal, exists := request.GetArguments()["search_intent"]; exists {
@@ -62,9 +77,19 @@ func (h *MCPHandler) HandleWebSearch(ctx context.Context, request mcp.CallToolRe
 
 	// Perform the search
 	opts := types.WebSearchOptions{
-		Query:        query,
-		SearchEngine: searchEngine,
-		SearchIntent: searchIntent,
+		Query:         query,
+		SearchEngine:  searchEngine,
+		SearchEngines: searchEngines,
+		SearchIntent:  searchIntent,
+	}
+
+	// Extract include_domains and exclude_domains parameters (optional, applied to result links)
+	opts.IncludeDomains = getStringSlice(request.GetArguments(), "include_domains")
+	opts.ExcludeDomains = getStringSlice(request.GetArguments(), "exclude_domains")
+
+	// Extract optional BigModel search parameters
+	if errMsg := parseSearchParameters(request.GetArguments(), &opts); errMsg != "" {
+		return mcp.NewToolResultError(errMsg), nil
 	}
 
 	searchResp, err := h.webSearchService.Search(ctx, opts)
@@ -72,11 +97,20 @@ func (h *MCPHandler) HandleWebSearch(ctx context.Context, request mcp.CallToolRe
 		return mcp.NewToolResultError(fmt.Sprintf("Search failed: %v", err)), nil
 	}
 
-	// Format the response
-	resultText := h.webSearchService.FormatSearchResponse(searchResp, query, searchEngine)
+	// Format the response, reporting the engines that were requested
+	requestedEngine := searchEngine
+	if len(searchEngines) > 0 {
+		requestedEngine = strings.Join(searchEngines, ", ")
+	}
+	resultText := h.webSearchService.FormatSearchResponse(searchResp, query, requestedEngine)
 	return mcp.NewToolResultText(resultText), nil
 }
 
+// RegisterSearchProvider makes an additional search backend selectable through ez_web_search
+func (h *MCPHandler) RegisterSearchProvider(provider services.SearchProvider) error {
+	return h.webSearchService.RegisterProvider(provider)
+}
+
 // HandleWebFetch handles web fetch tool requests
 func (h *MCPHandler) HandleWebFetch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Extract URL parameter
@@ -101,11 +135,156 @@ func (h *MCPHandler) HandleWebFetch(ctx context.Context, request mcp.CallToolReq
 		}
 	}
 
+	// Extract format parameter (optional, defaults to text)
+	format := services.FormatText
+	if formatVal, exists := request.GetArguments()["format"]; exists {
+		strVal, ok := formatVal.(string)
+		if !ok || (strVal != services.FormatText && strVal != services.FormatMarkdown && strVal != services.FormatHTML) {
+			return mcp.NewToolResultError("Invalid format parameter: must be text, markdown or html"), nil
+		}
+		format = strVal
+	}
+
+	// Extract link_style parameter (optional, only used by markdown output)
+	linkStyle := services.LinkStyleInline
+	if styleVal, exists := request.GetArguments()["link_style"]; exists {
+		strVal, ok := styleVal.(string)
+		if !ok || (strVal != services.LinkStyleInline && strVal != services.LinkStyleReference) {
+			return mcp.NewToolResultError("Invalid link_style parameter: must be inline or reference"), nil
+		}
+		linkStyle = strVal
+	}
+
+	// Extract start_index and max_length parameters (optional, used to page through long content)
+	startIndex := 0
+	if startVal, exists := request.GetArguments()["start_index"]; exists {
+		number, ok := startVal.(float64)
+		if !ok || number < 0 || number != float64(int(number)) {
+			return mcp.NewToolResultError("Invalid start_index parameter: must be a non-negative integer"), nil
+		}
+		startIndex = int(number)
+	}
+
+	maxLength := 0
+	if lengthVal, exists := request.GetArguments()["max_length"]; exists {
+		number, ok := lengthVal.(float64)
+		if !ok || number < 1 || number > maxFetchLength || number != float64(int(number)) {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid max_length parameter: must be an integer between 1 and %d", maxFetchLength)), nil
+		}
+		maxLength = int(number)
+	}
+
+	// Extract json_path parameter (optional, only used for JSON responses)
+	jsonPath := ""
+	if pathVal, exists := request.GetArguments()["json_path"]; exists {
+		strVal, ok := pathVal.(string)
+		if !ok {
+			return mcp.NewToolResultError("Invalid json_path parameter: must be a string"), nil
+		}
+		jsonPath = strings.TrimSpace(strVal)
+	}
+
+	// Extract include_structured_data parameter (optional, only used for HTML pages)
+	includeStructuredData := false
+	if structuredVal, exists := request.GetArguments()["include_structured_data"]; exists {
+		if boolVal, ok := structuredVal.(bool); ok {
+			includeStructuredData = boolVal
+		}
+	}
+
+	// Extract selector, xpath, extract, attribute and all_matches parameters (optional,
+	// used to target specific nodes of HTML pages)
+	selector, xpathQuery, extract, attribute := "", "", "", ""
+	stringParams := []struct {
+		name   string
+		target *string
+	}{{"selector", &selector}, {"xpath", &xpathQuery}, {"extract", &extract}, {"attribute", &attribute}}
+	for _, param := range stringParams {
+		if val, exists := request.GetArguments()[param.name]; exists {
+			strVal, ok := val.(string)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid %s parameter: must be a string", param.name)), nil
+			}
+			*param.target = strings.TrimSpace(strVal)
+		}
+	}
+	if selector != "" && xpathQuery != "" {
+		return mcp.NewToolResultError("Invalid parameters: use either selector or xpath, not both"), nil
+	}
+	if extract == "" && attribute != "" {
+		extract = services.ExtractAttribute
+	}
+	if extract != "" && extract != services.ExtractText && extract != services.ExtractHTML && extract != services.ExtractAttribute {
+		return mcp.NewToolResultError("Invalid extract parameter: must be text, html or attribute"), nil
+	}
+	if extract == services.ExtractAttribute && attribute == "" {
+		return mcp.NewToolResultError("Missing attribute parameter: required when extract is attribute"), nil
+	}
+	if extract != "" && selector == "" && xpathQuery == "" {
+		return mcp.NewToolResultError("Invalid parameters: extract and attribute require selector or xpath"), nil
+	}
+
+	allMatches := false
+	if allVal, exists := request.GetArguments()["all_matches"]; exists {
+		if boolVal, ok := allVal.(bool); ok {
+			allMatches = boolVal
+		}
+	}
+
+	// Extract confirm parameter (optional, required for URLs the policy marks for confirmation)
+	confirm := false
+	if confirmVal, exists := request.GetArguments()["confirm"]; exists {
+		if boolVal, ok := confirmVal.(bool); ok {
+			confirm = boolVal
+		}
+	}
+
+	// Extract redirect policy parameters (optional, override the configured defaults)
+	var maxRedirects *int
+	if redirectsVal, exists := request.GetArguments()["max_redirects"]; exists {
+		number, ok := redirectsVal.(float64)
+		if !ok || number < 0 || number > maxFetchRedirects || number != float64(int(number)) {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid max_redirects parameter: must be an integer between 0 and %d", maxFetchRedirects)), nil
+		}
+		value := int(number)
+		maxRedirects = &value
+	}
+
+	var allowCrossHost *bool
+	if crossHostVal, exists := request.GetArguments()["allow_cross_host_redirects"]; exists {
+		if boolVal, ok := crossHostVal.(bool); ok {
+			allowCrossHost = &boolVal
+		}
+	}
+
+	var refuseDowngrade *bool
+	if downgradeVal, exists := request.GetArguments()["refuse_https_downgrade"]; exists {
+		if boolVal, ok := downgradeVal.(bool); ok {
+			refuseDowngrade = &boolVal
+		}
+	}
+
 	// Fetch the web page
 	opts := types.WebFetchOptions{
 		URL:           targetURL,
 		IncludeLinks:  includeLinks,
 		IncludeImages: includeImages,
+		Format:        format,
+		LinkStyle:     linkStyle,
+		StartIndex:    startIndex,
+		MaxLength:     maxLength,
+		JSONPath:      jsonPath,
+
+		IncludeStructuredData:   includeStructuredData,
+		Selector:                selector,
+		XPath:                   xpathQuery,
+		Extract:                 extract,
+		Attribute:               attribute,
+		AllMatches:              allMatches,
+		Confirm:                 confirm,
+		MaxRedirects:            maxRedirects,
+		AllowCrossHostRedirects: allowCrossHost,
+		RefuseHTTPSDowngrade:    refuseDowngrade,
 	}
 
 	content, err := h.webFetchService.FetchWebPage(ctx, opts)
@@ -118,6 +297,105 @@ func (h *MCPHandler) HandleWebFetch(ctx context.Context, request mcp.CallToolReq
 	return mcp.NewToolResultText(resultText), nil
 }
 
+// HandleFeedRead handles feed reader tool requests
+func (h *MCPHandler) HandleFeedRead(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Extract URL parameter
+	feedURL, err := request.RequireString("url")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Missing or invalid url parameter: %v", err)), nil
+	}
+
+	opts := types.FeedOptions{
+		URL:   feedURL,
+		Limit: services.DefaultFeedLimit,
+	}
+
+	// Extract since parameter (optional, drops older entries)
+	if sinceVal, exists := request.GetArguments()["since"]; exists {
+		strVal, ok := sinceVal.(string)
+		if !ok {
+			return mcp.NewToolResultError("Invalid since parameter: must be a string"), nil
+		}
+		if strVal != "" {
+			since, err := services.ParseFeedSince(strVal, time.Now())
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid since parameter: %v", err)), nil
+			}
+			opts.Since = since
+		}
+	}
+
+	// Extract limit parameter (optional)
+	if limitVal, exists := request.GetArguments()["limit"]; exists {
+		number, ok := limitVal.(float64)
+		if !ok || number < 1 || number > services.MaxFeedLimit || number != float64(int(number)) {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid limit parameter: must be an integer between 1 and %d", services.MaxFeedLimit)), nil
+		}
+		opts.Limit = int(number)
+	}
+
+	feed, err := h.feedService.ReadFeed(ctx, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to read feed: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(h.feedService.FormatFeed(feed)), nil
+}
+
+// HandleSitemap handles sitemap tool requests
+func (h *MCPHandler) HandleSitemap(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// Extract URL parameter
+	siteURL, err := request.RequireString("url")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Missing or invalid url parameter: %v", err)), nil
+	}
+
+	opts := types.SitemapOptions{
+		URL:   siteURL,
+		Limit: services.DefaultSitemapLimit,
+	}
+
+	// Extract path_prefix parameter (optional)
+	if prefixVal, exists := request.GetArguments()["path_prefix"]; exists {
+		strVal, ok := prefixVal.(string)
+		if !ok {
+			return mcp.NewToolResultError("Invalid path_prefix parameter: must be a string"), nil
+		}
+		opts.PathPrefix = strings.TrimSpace(strVal)
+	}
+
+	// Extract since parameter (optional, drops URLs modified earlier)
+	if sinceVal, exists := request.GetArguments()["since"]; exists {
+		strVal, ok := sinceVal.(string)
+		if !ok {
+			return mcp.NewToolResultError("Invalid since parameter: must be a string"), nil
+		}
+		if strVal != "" {
+			since, err := services.ParseFeedSince(strVal, time.Now())
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid since parameter: %v", err)), nil
+			}
+			opts.Since = since
+		}
+	}
+
+	// Extract limit parameter (optional)
+	if limitVal, exists := request.GetArguments()["limit"]; exists {
+		number, ok := limitVal.(float64)
+		if !ok || number < 1 || number > services.MaxSitemapLimit || number != float64(int(number)) {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid limit parameter: must be an integer between 1 and %d", services.MaxSitemapLimit)), nil
+		}
+		opts.Limit = int(number)
+	}
+
+	sitemap, err := h.sitemapService.ReadSitemaps(ctx, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to read sitemap: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(h.sitemapService.FormatSitemap(sitemap)), nil
+}
+
 // HandlePing handles ping tool requests
 func (h *MCPHandler) HandlePing(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return mcp.NewToolResultText("pong"), nil
@@ -126,24 +404,56 @@ func (h *MCPHandler) HandlePing(ctx context.Context, request mcp.CallToolRequest
 // GetWebSearchTool returns the web search tool definition
 func (h *MCPHandler) GetWebSearchTool() mcp.Tool {
 	return mcp.NewTool("ez_web_search",
-		mcp.WithDescription("Search the web using BigModel Web Search API with configurable search engines"),
+		mcp.WithDescription("Search the web using BigModel Web Search API or other configured search providers"),
 		mcp.WithString("query",
 			mcp.Required(),
 			mcp.Description("The search query to execute"),
 		),
 		mcp.WithString("search_engine",
-			mcp.Description("Search engine to use: search_std (default), search_pro, search_pro_sogou, search_pro_quark"),
+			mcp.Description(fmt.Sprintf("Search engine to use: %s (default: %s)", strings.Join(h.webSearchService.ProviderNames(), ", "), h.config.Search.DefaultEngine)),
+		),
+		mcp.WithArray("search_engines",
+			mcp.Description("Query several engines concurrently and fuse the results with reciprocal-rank fusion; use \"all\" for every configured engine"),
+			mcp.WithStringItems(),
 		),
 		mcp.WithBoolean("search_intent",
 			mcp.Description("Whether to enable search intent analysis (default: false)"),
 		),
+		mcp.WithNumber("count",
+			mcp.Description("Number of results to return, 1-50 (default: engine specific)"),
+		),
+		mcp.WithString("search_domain_filter",
+			mcp.Description("Only return results from this domain, e.g. www.example.com"),
+		),
+		mcp.WithString("search_recency_filter",
+			mcp.Description("Only return results published within: day, week, month or year"),
+			mcp.Enum("day", "week", "month", "year"),
+		),
+		mcp.WithString("content_size",
+			mcp.Description("Length of result summaries: medium or high"),
+			mcp.Enum("medium", "high"),
+		),
+		mcp.WithArray("include_domains",
+			mcp.Description("Only keep results from these domains (subdomains included)"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("exclude_domains",
+			mcp.Description("Drop results from these domains (subdomains included)"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("request_id",
+			mcp.Description("Caller-supplied request ID passed through to the search API"),
+		),
+		mcp.WithString("user_id",
+			mcp.Description("End-user ID passed through to the search API for abuse monitoring (6-128 characters)"),
+		),
 	)
 }
 
 // GetWebFetchTool returns the web fetch tool definition
 func (h *MCPHandler) GetWebFetchTool() mcp.Tool {
 	return mcp.NewTool("ez_web_fetch",
-		mcp.WithDescription("Fetch and extract content from a web page with anti-bot protection"),
+		mcp.WithDescription("Fetch and extract content from a web page, PDF, JSON, XML, CSV or text document with anti-bot protection"),
 		mcp.WithString("url",
 			mcp.Required(),
 			mcp.Description("The URL of the web page to fetch"),
@@ -154,6 +464,91 @@ func (h *MCPHandler) GetWebFetchTool() mcp.Tool {
 		mcp.WithBoolean("include_images",
 			mcp.Description("Whether to include extracted images (default: false)"),
 		),
+		mcp.WithString("format",
+			mcp.Description("Output format of the main content: text (default), markdown (keeps headings, lists, code blocks and tables) or html"),
+			mcp.Enum(services.FormatText, services.FormatMarkdown, services.FormatHTML),
+		),
+		mcp.WithString("link_style",
+			mcp.Description("How links are rendered in markdown output: inline (default) or reference (numbered footnotes)"),
+			mcp.Enum(services.LinkStyleInline, services.LinkStyleReference),
+		),
+		mcp.WithNumber("start_index",
+			mcp.Description("Character offset to start reading from; use the next offset reported by a previous call to read long pages in chunks (default: 0)"),
+		),
+		mcp.WithNumber("max_length",
+			mcp.Description(fmt.Sprintf("Maximum number of characters to return (default: %d)", h.config.WebFetch.MaxContentSize)),
+		),
+		mcp.WithString("json_path",
+			mcp.Description("JSONPath query applied to JSON responses, e.g. $.items[0].name or $..id (default: whole document)"),
+		),
+		mcp.WithBoolean("include_structured_data",
+			mcp.Description("Whether to include JSON-LD, Microdata, RDFa, OpenGraph and Twitter Card data, the canonical URL, site name and publication dates of HTML pages (default: false)"),
+		),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector of the nodes to extract instead of the main content, e.g. table.prices or pre > code"),
+		),
+		mcp.WithString("xpath",
+			mcp.Description("XPath expression of the nodes to extract instead of the main content, e.g. //table[1] or //a/@href"),
+		),
+		mcp.WithString("extract",
+			mcp.Description("What to return for each selector or xpath match: text (default, rendered in the requested format), html (inner HTML) or attribute"),
+			mcp.Enum(services.ExtractText, services.ExtractHTML, services.ExtractAttribute),
+		),
+		mcp.WithString("attribute",
+			mcp.Description("Attribute to return for each match, e.g. href; implies extract=attribute"),
+		),
+		mcp.WithBoolean("all_matches",
+			mcp.Description("Whether to return every match as a list instead of only the first (default: false)"),
+		),
+		mcp.WithBoolean("confirm",
+			mcp.Description("Set to true only after the user has approved fetching a URL that the URL policy marks as requiring confirmation (default: false)"),
+		),
+		mcp.WithNumber("max_redirects",
+			mcp.Description(fmt.Sprintf("Maximum number of redirects to follow; 0 refuses any redirect (default: %d)", h.config.WebFetch.Redirects.MaxRedirects)),
+		),
+		mcp.WithBoolean("allow_cross_host_redirects",
+			mcp.Description(fmt.Sprintf("Whether to follow redirects to a different host than the requested one (default: %t)", h.config.WebFetch.Redirects.AllowCrossHost)),
+		),
+		mcp.WithBoolean("refuse_https_downgrade",
+			mcp.Description(fmt.Sprintf("Whether to refuse redirects from https to http (default: %t)", h.config.WebFetch.Redirects.RefuseHTTPSDowngrade)),
+		),
+	)
+}
+
+// GetFeedReadTool returns the feed reader tool definition
+func (h *MCPHandler) GetFeedReadTool() mcp.Tool {
+	return mcp.NewTool("ez_feed_read",
+		mcp.WithDescription("Read an RSS 2.0, Atom 1.0 or JSON Feed and return normalized entries; HTML pages are searched for advertised feeds"),
+		mcp.WithString("url",
+			mcp.Required(),
+			mcp.Description("The URL of the feed, or of a web page that links to its feed"),
+		),
+		mcp.WithString("since",
+			mcp.Description("Only return entries published after this time: an RFC 3339 timestamp, a date (YYYY-MM-DD) or a duration such as 24h or 7d"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of entries to return, newest first (default: %d, max: %d)", services.DefaultFeedLimit, services.MaxFeedLimit)),
+		),
+	)
+}
+
+// GetSitemapTool returns the sitemap tool definition
+func (h *MCPHandler) GetSitemapTool() mcp.Tool {
+	return mcp.NewTool("ez_sitemap",
+		mcp.WithDescription("List the URLs of a site from its sitemaps, discovered through robots.txt or /sitemap.xml; sitemap indexes and gzipped sitemaps are followed"),
+		mcp.WithString("url",
+			mcp.Required(),
+			mcp.Description("The URL of the site, or of a specific sitemap"),
+		),
+		mcp.WithString("path_prefix",
+			mcp.Description("Only return URLs whose path starts with this prefix, e.g. /docs/"),
+		),
+		mcp.WithString("since",
+			mcp.Description("Only return URLs last modified after this time: an RFC 3339 timestamp, a date (YYYY-MM-DD) or a duration such as 24h or 7d"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of URLs to return (default: %d, max: %d)", services.DefaultSitemapLimit, services.MaxSitemapLimit)),
+		),
 	)
 }
 
@@ -163,3 +558,89 @@ func (h *MCPHandler) GetPingTool() mcp.Tool {
 		mcp.WithDescription("Simple ping tool to test server connectivity"),
 	)
 }
+
+// parseSearchParameters validates the optional search API parameters and stores them in opts.
+// It returns a user-facing error message, or an empty string if all parameters are valid.
+func parseSearchParameters(args map[string]any, opts *types.WebSearchOptions) string {
+	if countVal, exists := args["count"]; exists {
+		count, ok := countVal.(float64)
+		if !ok || count != float64(int(count)) || count < 1 || count > 50 {
+			return "Invalid count parameter: must be an integer between 1 and 50"
+		}
+		opts.Count = int(count)
+	}
+
+	if domainVal, exists := args["search_domain_filter"]; exists {
+		domain, ok := domainVal.(string)
+		if !ok || strings.ContainsAny(strings.TrimSpace(domain), " /") {
+			return "Invalid search_domain_filter parameter: must be a bare domain name"
+		}
+		opts.DomainFilter = strings.TrimSpace(domain)
+	}
+
+	if recencyVal, exists := args["search_recency_filter"]; exists {
+		recency, ok := recencyVal.(string)
+		validRecency := map[string]bool{"day": true, "week": true, "month": true, "year": true}
+		if !ok || !validRecency[recency] {
+			return "Invalid search_recency_filter parameter: must be one of day, week, month, year"
+		}
+		opts.RecencyFilter = recency
+	}
+
+	if sizeVal, exists := args["content_size"]; exists {
+		size, ok := sizeVal.(string)
+		if !ok || (size != "medium" && size != "high") {
+			return "Invalid content_size parameter: must be medium or high"
+		}
+		opts.ContentSize = size
+	}
+
+	if requestIDVal, exists := args["request_id"]; exists {
+		requestID, ok := requestIDVal.(string)
+		if !ok || len(requestID) > 128 {
+			return "Invalid request_id parameter: must be a string of at most 128 characters"
+		}
+		opts.RequestID = requestID
+	}
+
+	if userIDVal, exists := args["user_id"]; exists {
+		userID, ok := userIDVal.(string)
+		if !ok || len(userID) < 6 || len(userID) > 128 {
+			return "Invalid user_id parameter: must be between 6 and 128 characters"
+		}
+		opts.UserID = userID
+	}
+
+	return ""
+}
+
+// getStringSlice extracts a list of non-empty strings from a tool argument
+func getStringSlice(args map[string]any, key string) []string {
+	rawVal, exists := args[key]
+	if !exists {
+		return nil
+	}
+
+	var values []string
+	switch v := rawVal.(type) {
+	case []any:
+		for _, item := range v {
+			if strVal, ok := item.(string); ok && strings.TrimSpace(strVal) != "" {
+				values = append(values, strings.TrimSpace(strVal))
+			}
+		}
+	case []string:
+		for _, item := range v {
+			if strings.TrimSpace(item) != "" {
+				values = append(values, strings.TrimSpace(item))
+			}
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if strings.TrimSpace(item) != "" {
+				values = append(values, strings.TrimSpace(item))
+			}
+		}
+	}
+	return values
+}
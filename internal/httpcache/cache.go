@@ -0,0 +1,296 @@
+// Package httpcache implements a private HTTP cache following RFC 9111 as an http.RoundTripper
+package httpcache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StatusHeader is set on every response passing through the cache to report how it was served
+const StatusHeader = "X-Cache"
+
+// Cache status values reported in StatusHeader
+const (
+	StatusHit         = "HIT"
+	StatusMiss        = "MISS"
+	StatusRevalidated = "REVALIDATED"
+	StatusStale       = "STALE"
+)
+
+// entry is a stored response together with the metadata needed for freshness and Vary matching
+type entry struct {
+	URL          string            `json:"url"`
+	StatusCode   int               `json:"status_code"`
+	Header       http.Header       `json:"header"`
+	Body         []byte            `json:"body"`
+	RequestTime  time.Time         `json:"request_time"`
+	ResponseTime time.Time         `json:"response_time"`
+	VaryValues   map[string]string `json:"vary_values"`
+}
+
+// date returns the Date header of the stored response, or the time it was received
+func (e *entry) date() time.Time {
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return date
+	}
+	return e.ResponseTime
+}
+
+// matchesVary reports whether req selects this entry under the stored Vary header
+func (e *entry) matchesVary(req *http.Request) bool {
+	for name, value := range e.VaryValues {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// response builds an http.Response serving the stored body
+func (e *entry) response(req *http.Request, status string) *http.Response {
+	header := e.Header.Clone()
+	header.Set(StatusHeader, status)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// Transport is an http.RoundTripper that serves fresh responses from a Store and
+// revalidates stale ones with If-None-Match / If-Modified-Since
+type Transport struct {
+	// Transport performs requests that cannot be served from the cache
+	Transport http.RoundTripper
+	// Store holds the cached entries
+	Store Store
+	// MaxEntrySize is the largest body stored; larger responses pass through uncached
+	MaxEntrySize int64
+	// MaxStaleOnError is how stale a stored response may be and still be served when the
+	// origin fails, in addition to what stale-if-error and max-stale allow
+	MaxStaleOnError time.Duration
+
+	now func() time.Time
+}
+
+// NewTransport creates a caching transport in front of base, using http.DefaultTransport when base is nil
+func NewTransport(base http.RoundTripper, store Store, maxEntrySize int64) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		Transport:    base,
+		Store:        store,
+		MaxEntrySize: maxEntrySize,
+		now:          time.Now,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req)
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp, err := t.Transport.RoundTrip(req)
+		// Unsafe methods invalidate the stored response for the target URI (RFC 9111 4.4)
+		if err == nil && resp.StatusCode < 400 {
+			t.Store.Delete(key)
+		}
+		return resp, err
+	}
+
+	if req.Method == http.MethodHead || req.Header.Get("Range") != "" {
+		return t.Transport.RoundTrip(req)
+	}
+
+	cached := t.load(key, req)
+	reqCC := parseCacheControl(req.Header)
+
+	if cached != nil && evaluateFreshness(req, cached, t.now()) == fresh {
+		return cached.response(req, StatusHit), nil
+	}
+
+	if reqCC.has("only-if-cached") {
+		return &http.Response{
+			Status:     "504 Gateway Timeout",
+			StatusCode: http.StatusGatewayTimeout,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{StatusHeader: []string{StatusMiss}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+
+	outReq := req
+	if cached != nil {
+		outReq = conditionalRequest(req, cached)
+	}
+
+	requestTime := t.now()
+	resp, err := t.Transport.RoundTrip(outReq)
+	if err != nil {
+		if cached != nil && canServeStaleOnError(req, cached, t.now(), t.MaxStaleOnError) {
+			return cached.response(req, StatusStale), nil
+		}
+		return nil, err
+	}
+	responseTime := t.now()
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		updated := updateEntry(cached, resp, requestTime, responseTime)
+		t.save(key, updated)
+		return updated.response(req, StatusRevalidated), nil
+	}
+
+	if cached != nil && resp.StatusCode >= 500 && canServeStaleOnError(req, cached, responseTime, t.MaxStaleOnError) {
+		resp.Body.Close()
+		return cached.response(req, StatusStale), nil
+	}
+
+	resp.Header.Set(StatusHeader, StatusMiss)
+	if !isStorable(req, resp) {
+		return resp, nil
+	}
+
+	e := &entry{
+		URL:          req.URL.String(),
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+		VaryValues:   varyValues(req, resp.Header),
+	}
+	e.Header.Del(StatusHeader)
+	resp.Body = &cachingBody{
+		ReadCloser: resp.Body,
+		limit:      t.MaxEntrySize,
+		onComplete: func(body []byte) {
+			e.Body = body
+			t.save(key, e)
+		},
+	}
+	return resp, nil
+}
+
+// load returns the stored entry for key if it matches the request's Vary headers
+func (t *Transport) load(key string, req *http.Request) *entry {
+	data, ok := t.Store.Get(key)
+	if !ok {
+		return nil
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Store.Delete(key)
+		return nil
+	}
+	if !e.matchesVary(req) {
+		return nil
+	}
+	return &e
+}
+
+// save serializes and stores an entry
+func (t *Transport) save(key string, e *entry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	t.Store.Set(key, data)
+}
+
+// cacheKey identifies the stored response for a request's target URI
+func cacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+// conditionalRequest clones req and adds validators from the stored response
+func conditionalRequest(req *http.Request, e *entry) *http.Request {
+	etag := e.Header.Get("ETag")
+	lastModified := e.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return req
+	}
+
+	conditional := req.Clone(req.Context())
+	if etag != "" && conditional.Header.Get("If-None-Match") == "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" && conditional.Header.Get("If-Modified-Since") == "" {
+		conditional.Header.Set("If-Modified-Since", lastModified)
+	}
+	return conditional
+}
+
+// updateEntry merges the headers of a 304 response into the stored entry (RFC 9111 4.3.4)
+func updateEntry(e *entry, resp *http.Response, requestTime, responseTime time.Time) *entry {
+	updated := *e
+	updated.Header = e.Header.Clone()
+	for name, values := range resp.Header {
+		// Content-Length and friends describe the empty 304 body, not the stored one
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", "Content-Range":
+			continue
+		}
+		updated.Header[name] = values
+	}
+	updated.RequestTime = requestTime
+	updated.ResponseTime = responseTime
+	return &updated
+}
+
+// varyValues records the request header values named by the response's Vary header
+func varyValues(req *http.Request, header http.Header) map[string]string {
+	values := make(map[string]string)
+	for _, line := range header.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name != "" {
+				values[name] = req.Header.Get(name)
+			}
+		}
+	}
+	return values
+}
+
+// cachingBody buffers a response body as it is read and stores it once fully consumed
+type cachingBody struct {
+	io.ReadCloser
+	buf        bytes.Buffer
+	limit      int64
+	overflow   bool
+	done       bool
+	onComplete func([]byte)
+}
+
+// Read reads from the underlying body, copying the data into the buffer
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.overflow {
+		if b.limit > 0 && int64(b.buf.Len()+n) > b.limit {
+			b.overflow = true
+			b.buf.Reset()
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !b.overflow && !b.done {
+		b.done = true
+		b.onComplete(bytes.Clone(b.buf.Bytes()))
+	}
+	return n, err
+}
@@ -0,0 +1,209 @@
+package httpcache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHeuristicLifetime caps heuristic freshness derived from Last-Modified
+const maxHeuristicLifetime = 24 * time.Hour
+
+// heuristicallyCacheable lists the status codes that may be cached without explicit freshness (RFC 9110 15.1)
+var heuristicallyCacheable = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// cacheControl holds parsed Cache-Control directives; directives without a value map to ""
+type cacheControl map[string]string
+
+// parseCacheControl parses all Cache-Control header lines
+func parseCacheControl(header http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, line := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(line, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(part, "=")
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return cc
+}
+
+// has reports whether the directive is present
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// seconds returns the delta-seconds value of a directive
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// isStorable reports whether a response to req may be stored (RFC 9111 3)
+func isStorable(req *http.Request, resp *http.Response) bool {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return false
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	respCC := parseCacheControl(resp.Header)
+	if reqCC.has("no-store") || respCC.has("no-store") {
+		return false
+	}
+
+	// Vary: * can never be matched by a later request
+	if strings.TrimSpace(resp.Header.Get("Vary")) == "*" {
+		return false
+	}
+
+	// Explicit freshness information makes any final status storable
+	if respCC.has("max-age") || respCC.has("public") || resp.Header.Get("Expires") != "" {
+		return true
+	}
+	return heuristicallyCacheable[resp.StatusCode]
+}
+
+// freshnessLifetime returns how long a stored response stays fresh (RFC 9111 4.2.1)
+func freshnessLifetime(e *entry) time.Duration {
+	cc := parseCacheControl(e.Header)
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		return maxAge
+	}
+
+	date := e.date()
+	if expires := e.Header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			// Invalid Expires values such as "0" mean already expired
+			return 0
+		}
+		if lifetime := expiresAt.Sub(date); lifetime > 0 {
+			return lifetime
+		}
+		return 0
+	}
+
+	// Heuristic freshness: 10% of the time since last modification
+	if heuristicallyCacheable[e.StatusCode] {
+		if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && date.After(lastModified) {
+			lifetime := date.Sub(lastModified) / 10
+			if lifetime > maxHeuristicLifetime {
+				lifetime = maxHeuristicLifetime
+			}
+			return lifetime
+		}
+	}
+	return 0
+}
+
+// currentAge returns the age of a stored response at now (RFC 9111 4.2.3)
+func currentAge(e *entry, now time.Time) time.Duration {
+	apparentAge := e.ResponseTime.Sub(e.date())
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+
+	var ageValue time.Duration
+	if n, err := strconv.ParseInt(strings.TrimSpace(e.Header.Get("Age")), 10, 64); err == nil && n > 0 {
+		ageValue = time.Duration(n) * time.Second
+	}
+	correctedAgeValue := ageValue + e.ResponseTime.Sub(e.RequestTime)
+
+	correctedInitialAge := apparentAge
+	if correctedAgeValue > correctedInitialAge {
+		correctedInitialAge = correctedAgeValue
+	}
+
+	return correctedInitialAge + now.Sub(e.ResponseTime)
+}
+
+// freshness describes whether a stored response can be served for a request
+type freshness int
+
+const (
+	// fresh responses are served without contacting the origin
+	fresh freshness = iota
+	// stale responses must be revalidated before use
+	stale
+)
+
+// evaluateFreshness applies response and request directives to decide whether e can be served as is
+func evaluateFreshness(req *http.Request, e *entry, now time.Time) freshness {
+	reqCC := parseCacheControl(req.Header)
+	respCC := parseCacheControl(e.Header)
+
+	if reqCC.has("no-cache") || respCC.has("no-cache") || strings.Contains(req.Header.Get("Pragma"), "no-cache") {
+		return stale
+	}
+
+	lifetime := freshnessLifetime(e)
+	age := currentAge(e, now)
+
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return stale
+	}
+	if minFresh, ok := reqCC.seconds("min-fresh"); ok {
+		age += minFresh
+	}
+
+	if age < lifetime {
+		return fresh
+	}
+
+	// max-stale lets the client accept a stale response unless the origin forbids it
+	if reqCC.has("max-stale") && !respCC.has("must-revalidate") {
+		maxStale, ok := reqCC.seconds("max-stale")
+		if !ok || age-lifetime <= maxStale {
+			return fresh
+		}
+	}
+	return stale
+}
+
+// canServeStaleOnError reports whether e may be served at now when the origin cannot be reached
+// or answers with a server error. A stale response is only served within the staleness allowed
+// by stale-if-error on the response or request (RFC 5861), max-stale on the request, or
+// maxStale configured for the cache (RFC 9111 4.2.4).
+func canServeStaleOnError(req *http.Request, e *entry, now time.Time, maxStale time.Duration) bool {
+	reqCC := parseCacheControl(req.Header)
+	respCC := parseCacheControl(e.Header)
+	if respCC.has("must-revalidate") || respCC.has("proxy-revalidate") || respCC.has("no-cache") {
+		return false
+	}
+
+	staleness := currentAge(e, now) - freshnessLifetime(e)
+	if staleness <= 0 {
+		return true
+	}
+
+	allowed := maxStale
+	for _, cc := range []cacheControl{respCC, reqCC} {
+		if value, ok := cc.seconds("stale-if-error"); ok && value > allowed {
+			allowed = value
+		}
+	}
+	if reqCC.has("max-stale") {
+		value, ok := reqCC.seconds("max-stale")
+		if !ok {
+			// max-stale without a value accepts a response of any staleness
+			return true
+		}
+		allowed = max(allowed, value)
+	}
+	return staleness <= allowed
+}
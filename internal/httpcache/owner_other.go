@@ -0,0 +1,10 @@
+//go:build !unix
+
+package httpcache
+
+import "os"
+
+// checkOwner is a no-op where file ownership is not exposed as a uid
+func checkOwner(info os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package httpcache
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkOwner reports an error unless the current user owns the file
+func checkOwner(info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if uid := os.Getuid(); int(stat.Uid) != uid {
+		return fmt.Errorf("owned by uid %d, not the current user (uid %d)", stat.Uid, uid)
+	}
+	return nil
+}
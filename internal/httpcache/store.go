@@ -0,0 +1,294 @@
+package httpcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store persists serialized cache entries by key
+type Store interface {
+	// Get returns the entry stored under key
+	Get(key string) ([]byte, bool)
+	// Set stores an entry under key, replacing any previous entry
+	Set(key string, value []byte)
+	// Delete removes the entry stored under key
+	Delete(key string)
+}
+
+// memoryItem is an entry held in the memory store's LRU list
+type memoryItem struct {
+	key   string
+	value []byte
+}
+
+// MemoryStore is an in-memory LRU store bounded by entry count and total bytes
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	size       int64
+	items      map[string]*list.Element
+	lru        *list.List
+}
+
+// NewMemoryStore creates an in-memory LRU store; non-positive limits are ignored
+func NewMemoryStore(maxEntries int, maxBytes int64) *MemoryStore {
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		items:      make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get returns the entry stored under key and marks it as recently used
+func (m *MemoryStore) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	m.lru.MoveToFront(element)
+	return element.Value.(*memoryItem).value, true
+}
+
+// Set stores an entry and evicts the least recently used entries beyond the limits
+func (m *MemoryStore) Set(key string, value []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// An entry larger than the whole store would only evict everything else
+	if m.maxBytes > 0 && int64(len(value)) > m.maxBytes {
+		m.deleteLocked(key)
+		return
+	}
+
+	if element, ok := m.items[key]; ok {
+		item := element.Value.(*memoryItem)
+		m.size += int64(len(value) - len(item.value))
+		item.value = value
+		m.lru.MoveToFront(element)
+	} else {
+		m.items[key] = m.lru.PushFront(&memoryItem{key: key, value: value})
+		m.size += int64(len(value))
+	}
+
+	for m.lru.Len() > 0 && ((m.maxEntries > 0 && m.lru.Len() > m.maxEntries) || (m.maxBytes > 0 && m.size > m.maxBytes)) {
+		m.deleteLocked(m.lru.Back().Value.(*memoryItem).key)
+	}
+}
+
+// Delete removes the entry stored under key
+func (m *MemoryStore) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteLocked(key)
+}
+
+// deleteLocked removes an entry; the caller must hold m.mu
+func (m *MemoryStore) deleteLocked(key string) {
+	element, ok := m.items[key]
+	if !ok {
+		return
+	}
+	m.size -= int64(len(element.Value.(*memoryItem).value))
+	m.lru.Remove(element)
+	delete(m.items, key)
+}
+
+// diskItem is an entry file tracked in the disk store's LRU list
+type diskItem struct {
+	name string
+	size int64
+}
+
+// DiskStore stores each entry as a file in a private directory, named by the SHA-256 of its key,
+// and evicts the least recently used files beyond its entry count and byte limits
+type DiskStore struct {
+	mu         sync.Mutex
+	dir        string
+	maxEntries int
+	maxBytes   int64
+	size       int64
+	items      map[string]*list.Element
+	lru        *list.List
+}
+
+// NewDiskStore creates a disk store rooted at dir, creating the directory with mode 0700 if
+// needed. A directory owned by another user, or writable by others, is refused since its
+// entries could be read or planted by them. Non-positive limits are ignored.
+func NewDiskStore(dir string, maxEntries int, maxBytes int64) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect cache directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("cache directory %s is not a directory", dir)
+	}
+	if err := checkOwner(info); err != nil {
+		return nil, fmt.Errorf("refusing cache directory %s: %w", dir, err)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		if err := os.Chmod(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to restrict cache directory permissions: %w", err)
+		}
+	}
+
+	d := &DiskStore{
+		dir:        dir,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		items:      make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// load indexes the entries already on disk, oldest first, and removes leftover temporary files
+func (d *DiskStore) load() error {
+	dirEntries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	type stored struct {
+		item    *diskItem
+		modTime time.Time
+	}
+	var files []stored
+	for _, dirEntry := range dirEntries {
+		if strings.HasPrefix(dirEntry.Name(), ".tmp-") {
+			os.Remove(filepath.Join(d.dir, dirEntry.Name()))
+			continue
+		}
+		if !dirEntry.Type().IsRegular() {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, stored{item: &diskItem{name: dirEntry.Name(), size: info.Size()}, modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, file := range files {
+		d.items[file.item.name] = d.lru.PushFront(file.item)
+		d.size += file.item.size
+	}
+	d.evictLocked()
+	return nil
+}
+
+// Get returns the entry stored under key and marks it as recently used
+func (d *DiskStore) Get(key string) ([]byte, bool) {
+	name := d.name(key)
+	value, err := os.ReadFile(filepath.Join(d.dir, name))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	element, ok := d.items[name]
+	if err != nil || !ok {
+		if ok {
+			d.removeLocked(element)
+		}
+		return nil, false
+	}
+	d.lru.MoveToFront(element)
+	return value, true
+}
+
+// Set stores an entry under key, writing through a temporary file so readers never see partial
+// data, and evicts the least recently used entries beyond the limits
+func (d *DiskStore) Set(key string, value []byte) {
+	name := d.name(key)
+	// An entry larger than the whole store would only evict everything else
+	if d.maxBytes > 0 && int64(len(value)) > d.maxBytes {
+		d.Delete(key)
+		return
+	}
+
+	tmp, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.Rename(tmp.Name(), filepath.Join(d.dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if element, ok := d.items[name]; ok {
+		item := element.Value.(*diskItem)
+		d.size += int64(len(value)) - item.size
+		item.size = int64(len(value))
+		d.lru.MoveToFront(element)
+	} else {
+		d.items[name] = d.lru.PushFront(&diskItem{name: name, size: int64(len(value))})
+		d.size += int64(len(value))
+	}
+	d.evictLocked()
+}
+
+// Delete removes the entry stored under key
+func (d *DiskStore) Delete(key string) {
+	name := d.name(key)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if element, ok := d.items[name]; ok {
+		d.removeLocked(element)
+		return
+	}
+	os.Remove(filepath.Join(d.dir, name))
+}
+
+// evictLocked removes the least recently used entries beyond the limits; the caller must hold d.mu
+func (d *DiskStore) evictLocked() {
+	for d.lru.Len() > 0 && ((d.maxEntries > 0 && d.lru.Len() > d.maxEntries) || (d.maxBytes > 0 && d.size > d.maxBytes)) {
+		d.removeLocked(d.lru.Back())
+	}
+}
+
+// removeLocked deletes an entry file and forgets it; the caller must hold d.mu
+func (d *DiskStore) removeLocked(element *list.Element) {
+	item := element.Value.(*diskItem)
+	os.Remove(filepath.Join(d.dir, item.name))
+	d.size -= item.size
+	d.lru.Remove(element)
+	delete(d.items, item.name)
+}
+
+// name returns the file name for key
+func (d *DiskStore) name(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,266 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Action is the outcome of a policy rule
+type Action string
+
+// Policy actions
+const (
+	Allow   Action = "allow"
+	Deny    Action = "deny"
+	Confirm Action = "confirm"
+)
+
+// ErrDenied is matched by errors.Is for every DeniedError
+var ErrDenied = errors.New("denied by URL policy")
+
+// ErrConfirmationRequired is matched by errors.Is for every ConfirmationRequiredError
+var ErrConfirmationRequired = errors.New("URL policy requires confirmation")
+
+// DeniedError reports a URL refused by the policy
+type DeniedError struct {
+	URL  string
+	Rule string
+}
+
+// Error implements the error interface
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("refused by URL policy: access to %s is denied (%s)", e.URL, e.Rule)
+}
+
+// Is makes errors.Is(err, ErrDenied) match
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+// ConfirmationRequiredError reports a URL that may only be accessed with explicit confirmation
+type ConfirmationRequiredError struct {
+	URL  string
+	Rule string
+}
+
+// Error implements the error interface
+func (e *ConfirmationRequiredError) Error() string {
+	return fmt.Sprintf("URL policy requires confirmation to access %s (%s); retry with confirm=true once the user has approved it", e.URL, e.Rule)
+}
+
+// Is makes errors.Is(err, ErrConfirmationRequired) match
+func (e *ConfirmationRequiredError) Is(target error) bool {
+	return target == ErrConfirmationRequired
+}
+
+// Decision is the action that applies to a URL and the rule that selected it
+type Decision struct {
+	Action Action
+	// Rule describes the matching rule, or the default action
+	Rule string
+}
+
+// Rule matches URLs by host, path and scheme; every condition that is set must match
+type Rule struct {
+	Action Action
+	host   *regexp.Regexp
+	path   *regexp.Regexp
+	scheme *regexp.Regexp
+	source string
+}
+
+// String returns the rule as written in the configuration
+func (r *Rule) String() string {
+	return r.source
+}
+
+// Matches reports whether a URL satisfies all conditions of the rule
+func (r *Rule) Matches(u *url.URL) bool {
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return (r.host == nil || r.host.MatchString(host)) &&
+		(r.path == nil || r.path.MatchString(path)) &&
+		(r.scheme == nil || r.scheme.MatchString(strings.ToLower(u.Scheme)))
+}
+
+// ParseRule parses a rule of the form "<action> <field>:<pattern> ...", e.g.
+// "deny host:*.example.com path:/admin/*". Fields are host, path and scheme. Patterns are globs
+// (* matches any run of characters, ? a single one) unless written as /regexp/.
+func ParseRule(line string) (*Rule, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty policy rule")
+	}
+
+	rule := &Rule{source: strings.Join(fields, " ")}
+	action, err := ParseAction(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("policy rule %q: %w", line, err)
+	}
+	rule.Action = action
+
+	if len(fields) == 1 {
+		return nil, fmt.Errorf("policy rule %q: at least one host, path or scheme condition is required", line)
+	}
+	for _, condition := range fields[1:] {
+		name, pattern, ok := strings.Cut(condition, ":")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("policy rule %q: condition %q must have the form field:pattern", line, condition)
+		}
+
+		// Host names and schemes are case-insensitive, paths are not
+		var target **regexp.Regexp
+		caseInsensitive := true
+		switch strings.ToLower(name) {
+		case "host":
+			target = &rule.host
+		case "path":
+			target = &rule.path
+			caseInsensitive = false
+		case "scheme":
+			target = &rule.scheme
+		default:
+			return nil, fmt.Errorf("policy rule %q: unknown field %q (use host, path or scheme)", line, name)
+		}
+		if *target != nil {
+			return nil, fmt.Errorf("policy rule %q: duplicate %s condition", line, name)
+		}
+
+		compiled, err := compilePattern(pattern, caseInsensitive)
+		if err != nil {
+			return nil, fmt.Errorf("policy rule %q: %w", line, err)
+		}
+		*target = compiled
+	}
+	return rule, nil
+}
+
+// ParseAction parses an action name
+func ParseAction(value string) (Action, error) {
+	switch action := Action(strings.ToLower(strings.TrimSpace(value))); action {
+	case Allow, Deny, Confirm:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown policy action %q (use allow, deny or confirm)", value)
+	}
+}
+
+// compilePattern compiles a /regexp/ or a glob into an anchored regular expression
+func compilePattern(pattern string, caseInsensitive bool) (*regexp.Regexp, error) {
+	var expr string
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		expr = pattern[1 : len(pattern)-1]
+	} else {
+		var glob strings.Builder
+		glob.WriteString("^")
+		for _, r := range pattern {
+			switch r {
+			case '*':
+				glob.WriteString(".*")
+			case '?':
+				glob.WriteString(".")
+			default:
+				glob.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		glob.WriteString("$")
+		expr = glob.String()
+	}
+	if caseInsensitive {
+		expr = "(?i)" + expr
+	}
+
+	compiled, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return compiled, nil
+}
+
+// Engine evaluates URLs against an ordered list of rules; the first matching rule wins and
+// URLs that match no rule get the default action
+type Engine struct {
+	rules         []*Rule
+	defaultAction Action
+}
+
+// New creates an engine from rule lines and a default action (allow when empty).
+// Blank lines and lines starting with # are ignored.
+func New(defaultAction string, rules []string) (*Engine, error) {
+	engine := &Engine{defaultAction: Allow}
+	if strings.TrimSpace(defaultAction) != "" {
+		action, err := ParseAction(defaultAction)
+		if err != nil {
+			return nil, fmt.Errorf("default policy action: %w", err)
+		}
+		engine.defaultAction = action
+	}
+
+	for _, line := range rules {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseRule(line)
+		if err != nil {
+			return nil, err
+		}
+		engine.rules = append(engine.rules, rule)
+	}
+	return engine, nil
+}
+
+// Evaluate returns the decision for a URL
+func (e *Engine) Evaluate(u *url.URL) Decision {
+	for _, rule := range e.rules {
+		if rule.Matches(u) {
+			return Decision{Action: rule.Action, Rule: fmt.Sprintf("rule %q", rule.String())}
+		}
+	}
+	return Decision{Action: e.defaultAction, Rule: fmt.Sprintf("default action %s", e.defaultAction)}
+}
+
+// Check returns a DeniedError or, unless confirmed is set, a ConfirmationRequiredError when
+// the policy does not allow the URL
+func (e *Engine) Check(u *url.URL, confirmed bool) error {
+	decision := e.Evaluate(u)
+	switch decision.Action {
+	case Deny:
+		return &DeniedError{URL: u.String(), Rule: decision.Rule}
+	case Confirm:
+		if !confirmed {
+			return &ConfirmationRequiredError{URL: u.String(), Rule: decision.Rule}
+		}
+	}
+	return nil
+}
+
+// confirmationKey is the context key of the URL the user has confirmed
+type confirmationKey struct{}
+
+// WithConfirmation records in a context that the user approved u, so that a redirect chain
+// passing through u again is followed. The approval covers no other URL.
+func WithConfirmation(ctx context.Context, u *url.URL) context.Context {
+	return context.WithValue(ctx, confirmationKey{}, confirmationTarget(u))
+}
+
+// Confirmed reports whether a context carries the user's approval of u
+func Confirmed(ctx context.Context, u *url.URL) bool {
+	approved, ok := ctx.Value(confirmationKey{}).(string)
+	return ok && approved == confirmationTarget(u)
+}
+
+// confirmationTarget identifies a confirmed URL; the fragment is never sent and is ignored
+func confirmationTarget(u *url.URL) string {
+	target := *u
+	target.Fragment = ""
+	target.RawFragment = ""
+	return target.String()
+}
@@ -0,0 +1,143 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		line    string
+		action  Action
+		source  string
+		wantErr bool
+	}{
+		{line: "deny host:*.example.com", action: Deny, source: "deny host:*.example.com"},
+		{line: "  CONFIRM   host:example.com  path:/admin/* ", action: Confirm, source: "CONFIRM host:example.com path:/admin/*"},
+		{line: "allow scheme:https host:/^(www\\.)?example\\.org$/", action: Allow, source: "allow scheme:https host:/^(www\\.)?example\\.org$/"},
+		{line: "", wantErr: true},
+		{line: "block host:example.com", wantErr: true},
+		{line: "deny", wantErr: true},
+		{line: "deny host", wantErr: true},
+		{line: "deny host:", wantErr: true},
+		{line: "deny port:80", wantErr: true},
+		{line: "deny host:a.com host:b.com", wantErr: true},
+		{line: "deny host:/[/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			rule, err := ParseRule(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRule(%q) = %v, want an error", tt.line, rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRule(%q) failed: %v", tt.line, err)
+			}
+			if rule.Action != tt.action || rule.String() != tt.source {
+				t.Errorf("ParseRule(%q) = %s %q, want %s %q", tt.line, rule.Action, rule.String(), tt.action, tt.source)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	engine, err := New("confirm", []string{
+		"# comment",
+		"",
+		"deny host:*.facebook.com",
+		"deny scheme:http host:/.*\\.internal$/",
+		"allow host:example.com path:/public/*",
+		"deny host:example.com",
+		"allow host:docs.example.org path:/v?/*",
+		"allow host:*.example.org",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url    string
+		action Action
+		rule   string
+	}{
+		{"https://www.facebook.com/profile", Deny, `rule "deny host:*.facebook.com"`},
+		{"https://WWW.Facebook.COM./", Deny, `rule "deny host:*.facebook.com"`},
+		{"https://facebook.com/", Confirm, "default action confirm"},
+		{"http://build.internal/status", Deny, `rule "deny scheme:http host:/.*\\.internal$/"`},
+		{"HTTP://build.internal/status", Deny, `rule "deny scheme:http host:/.*\\.internal$/"`},
+		{"https://build.internal/status", Confirm, "default action confirm"},
+		{"https://example.com/public/page", Allow, `rule "allow host:example.com path:/public/*"`},
+		{"https://example.com/PUBLIC/page", Deny, `rule "deny host:example.com"`},
+		{"https://example.com", Deny, `rule "deny host:example.com"`},
+		{"https://docs.example.org/v2/guide", Allow, `rule "allow host:docs.example.org path:/v?/*"`},
+		{"https://docs.example.org/v10/guide", Allow, `rule "allow host:*.example.org"`},
+		{"https://example.org/", Confirm, "default action confirm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decision := engine.Evaluate(u)
+			if decision.Action != tt.action || decision.Rule != tt.rule {
+				t.Errorf("Evaluate(%s) = %s (%s), want %s (%s)", tt.url, decision.Action, decision.Rule, tt.action, tt.rule)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	engine, err := New("allow", []string{"deny host:denied.example", "confirm host:confirm.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url       string
+		confirmed bool
+		want      error
+	}{
+		{"https://allowed.example/", false, nil},
+		{"https://denied.example/", false, ErrDenied},
+		{"https://denied.example/", true, ErrDenied},
+		{"https://confirm.example/", false, ErrConfirmationRequired},
+		{"https://confirm.example/", true, nil},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if err := engine.Check(u, tt.confirmed); !errors.Is(err, tt.want) {
+			t.Errorf("Check(%s, %v) = %v, want %v", tt.url, tt.confirmed, err, tt.want)
+		}
+	}
+}
+
+func TestConfirmed(t *testing.T) {
+	approved, _ := url.Parse("https://confirm.example/page?id=1#section")
+	ctx := WithConfirmation(context.Background(), approved)
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://confirm.example/page?id=1#section", true},
+		{"https://confirm.example/page?id=1", true},
+		{"https://confirm.example/page?id=2", false},
+		{"https://confirm.example/other", false},
+		{"http://confirm.example/page?id=1", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := Confirmed(ctx, u); got != tt.want {
+			t.Errorf("Confirmed(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+	if Confirmed(context.Background(), approved) {
+		t.Errorf("Confirmed without WithConfirmation = true, want false")
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ez-web-search/internal/config"
+	"ez-web-search/internal/utils"
+	"ez-web-search/pkg/types"
+)
+
+// BigModelEngines lists the search engines offered by the BigModel Web Search API
+var BigModelEngines = []string{
+	"search_std",
+	"search_pro",
+	"search_pro_sogou",
+	"search_pro_quark",
+}
+
+// bigModelRecencyFilters maps provider-neutral recency values to BigModel's search_recency_filter
+var bigModelRecencyFilters = map[string]string{
+	"day":   "oneDay",
+	"week":  "oneWeek",
+	"month": "oneMonth",
+	"year":  "oneYear",
+}
+
+// BigModelProvider searches through one engine of the BigModel Web Search API
+type BigModelProvider struct {
+	config     *config.Config
+	engine     string
+	httpClient *http.Client
+	antiBot    *utils.AntiBotManager
+}
+
+// NewBigModelProvider creates a BigModel provider bound to a single search engine
+func NewBigModelProvider(cfg *config.Config, engine string, httpClient *http.Client, antiBot *utils.AntiBotManager) *BigModelProvider {
+	return &BigModelProvider{
+		config:     cfg,
+		engine:     engine,
+		httpClient: httpClient,
+		antiBot:    antiBot,
+	}
+}
+
+// Name returns the BigModel engine name, e.g. search_std
+func (p *BigModelProvider) Name() string {
+	return p.engine
+}
+
+// Search performs a web search using BigModel API
+func (p *BigModelProvider) Search(ctx context.Context, opts types.WebSearchOptions) (*types.SearchResponse, error) {
+	reqBody := types.WebSearchRequest{
+		SearchQuery:         opts.Query,
+		SearchEngine:        p.engine,
+		SearchIntent:        opts.SearchIntent,
+		Count:               opts.Count,
+		SearchDomainFilter:  opts.DomainFilter,
+		SearchRecencyFilter: bigModelRecencyFilters[opts.RecencyFilter],
+		ContentSize:         opts.ContentSize,
+		RequestID:           opts.RequestID,
+		UserID:              opts.UserID,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.config.BigModel.BaseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set authentication and content type
+	req.Header.Set("Authorization", "Bearer "+p.config.BigModel.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set anti-bot headers
+	userAgent := p.antiBot.GetRandomUserAgent()
+	p.antiBot.SetRealisticHeaders(req, userAgent)
+
+	// Apply random delay if configured
+	if p.config.WebFetch.UserAgentRotate && p.antiBot.ShouldDelay() {
+		delay := p.antiBot.GetRandomDelay(p.config.WebFetch.DelayMin, p.config.WebFetch.DelayMax)
+		time.Sleep(delay)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check for rate limiting or blocking
+	if p.antiBot.IsRateLimited(resp) {
+		return nil, &ProviderError{
+			Provider:    p.engine,
+			StatusCode:  resp.StatusCode,
+			RateLimited: true,
+			Err:         fmt.Errorf("request was rate limited, status: %d", resp.StatusCode),
+		}
+	}
+
+	if p.antiBot.IsBlocked(resp) {
+		return nil, &ProviderError{
+			Provider:   p.engine,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("request was blocked, status: %d", resp.StatusCode),
+		}
+	}
+
+	// Read and decode the response body within the size limit
+	body, err := utils.ReadResponseBody(resp, p.config.Search.MaxResponseSize, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ProviderError{
+			Provider:   p.engine,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body)),
+		}
+	}
+
+	// Decode JSON response
+	var searchResp types.WebSearchResponse
+	if err := json.Unmarshal(body, &searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &types.SearchResponse{
+		Provider:     p.engine,
+		RequestID:    searchResp.RequestID,
+		SearchIntent: searchResp.SearchIntent,
+		SearchResult: searchResp.SearchResult,
+	}, nil
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"ez-web-search/internal/utils"
+	"ez-web-search/pkg/types"
+)
+
+// Content formats reported for documents that are not rendered from HTML
+const (
+	FormatJSON   = "json"
+	FormatXML    = "xml"
+	FormatCSV    = "csv"
+	FormatBinary = "binary"
+)
+
+// FetchedDocument is a downloaded response body handed to a content handler
+type FetchedDocument struct {
+	// URL is the URL the body was served from after redirects, the base of relative links
+	URL *url.URL
+	// Redirects are the redirect hops followed to reach URL
+	Redirects []types.RedirectHop
+	// StatusCode and Header are taken from the HTTP response
+	StatusCode int
+	Header     http.Header
+	// MediaType is the resolved media type, without parameters
+	MediaType string
+	// ContentType is the raw Content-Type header
+	ContentType string
+	// Body is the decoded (decompressed) response body
+	Body []byte
+	// Truncated is true when Body holds only the beginning of an oversized response
+	Truncated bool
+	// Options are the options of the fetch request
+	Options types.WebFetchOptions
+}
+
+// ContentHandler extracts content from response bodies of the media types it is registered for
+type ContentHandler interface {
+	// Extract fills content from the fetched document
+	Extract(doc *FetchedDocument, content *types.WebPageContent) error
+}
+
+// ContentHandlerFunc adapts a function to the ContentHandler interface
+type ContentHandlerFunc func(doc *FetchedDocument, content *types.WebPageContent) error
+
+// Extract calls f(doc, content)
+func (f ContentHandlerFunc) Extract(doc *FetchedDocument, content *types.WebPageContent) error {
+	return f(doc, content)
+}
+
+// ContentHandlerRegistry maps media types to content handlers.
+// Patterns are exact media types ("application/json"), structured syntax suffixes ("+json")
+// or type wildcards ("text/*"); lookups try them in that order before the fallback handler.
+type ContentHandlerRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]ContentHandler
+	fallback ContentHandler
+}
+
+// NewContentHandlerRegistry creates a registry that uses fallback for unregistered media types
+func NewContentHandlerRegistry(fallback ContentHandler) *ContentHandlerRegistry {
+	return &ContentHandlerRegistry{
+		handlers: make(map[string]ContentHandler),
+		fallback: fallback,
+	}
+}
+
+// Register associates a handler with one or more media type patterns
+func (r *ContentHandlerRegistry) Register(handler ContentHandler, patterns ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pattern := range patterns {
+		r.handlers[strings.ToLower(pattern)] = handler
+	}
+}
+
+// Lookup returns the handler for a media type
+func (r *ContentHandlerRegistry) Lookup(mediaType string) ContentHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mediaType = strings.ToLower(mediaType)
+	if handler, ok := r.handlers[mediaType]; ok {
+		return handler
+	}
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if handler, ok := r.handlers[mediaType[i:]]; ok {
+			return handler
+		}
+	}
+	if i := strings.Index(mediaType, "/"); i >= 0 {
+		if handler, ok := r.handlers[mediaType[:i]+"/*"]; ok {
+			return handler
+		}
+	}
+	return r.fallback
+}
+
+// extensionMediaTypes resolves generic Content-Types (text/plain, octet-stream) by file extension,
+// as served for raw repository files
+var extensionMediaTypes = map[string]string{
+	".json":     "application/json",
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+	".csv":      "text/csv",
+	".tsv":      "text/tab-separated-values",
+	".xml":      "application/xml",
+	".pdf":      "application/pdf",
+	".txt":      "text/plain",
+}
+
+// resolveMediaType determines the media type of a response from its Content-Type header,
+// falling back to the URL extension and content sniffing for missing or generic types
+func resolveMediaType(contentType string, pageURL *url.URL, body []byte) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = ""
+	}
+	mediaType = strings.ToLower(mediaType)
+
+	if mediaType == "" || mediaType == "text/plain" || mediaType == "application/octet-stream" {
+		byExtension, ok := extensionMediaTypes[strings.ToLower(path.Ext(pageURL.Path))]
+		if ok && (byExtension != "application/pdf" || bytes.HasPrefix(body, []byte("%PDF-"))) {
+			return byExtension
+		}
+		if bytes.HasPrefix(body, []byte("%PDF-")) {
+			return "application/pdf"
+		}
+	}
+
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	return mediaType
+}
+
+// newContentHandlers registers the built-in handlers of the web fetch service
+func (s *WebFetchService) newContentHandlers() *ContentHandlerRegistry {
+	registry := NewContentHandlerRegistry(ContentHandlerFunc(extractBinarySummary))
+	registry.Register(ContentHandlerFunc(s.extractHTML), "text/html", "application/xhtml+xml")
+	registry.Register(ContentHandlerFunc(s.extractPDF), "application/pdf", "application/x-pdf")
+	registry.Register(ContentHandlerFunc(extractJSON), "application/json", "text/json", "+json")
+	registry.Register(ContentHandlerFunc(extractXML), "application/xml", "text/xml", "+xml")
+	registry.Register(ContentHandlerFunc(extractCSV), "text/csv", "application/csv", "text/tab-separated-values")
+	registry.Register(ContentHandlerFunc(extractPlainText), "text/*", "application/javascript",
+		"application/x-yaml", "application/yaml", "application/toml", "application/x-sh")
+	return registry
+}
+
+// RegisterContentHandler adds or replaces the handler for the given media type patterns
+func (s *WebFetchService) RegisterContentHandler(handler ContentHandler, patterns ...string) {
+	s.handlers.Register(handler, patterns...)
+}
+
+// extractPDF extracts the text and metadata of a PDF document
+func (s *WebFetchService) extractPDF(doc *FetchedDocument, content *types.WebPageContent) error {
+	if doc.Truncated {
+		return fmt.Errorf("PDF document exceeds the limit of %d bytes and cannot be partially parsed", s.config.WebFetch.MaxBodySize)
+	}
+	return extractPDFContent(doc.Body, content)
+}
+
+// decodeText transcodes a textual body to UTF-8 and records the detected charset
+func decodeText(doc *FetchedDocument, content *types.WebPageContent) ([]byte, error) {
+	detected := utils.DetectCharset(doc.Body, doc.ContentType)
+	body, err := utils.ToUTF8(doc.Body, detected)
+	if err != nil {
+		return nil, err
+	}
+	content.Charset = detected.Name
+	content.CharsetSource = detected.Source
+	return body, nil
+}
+
+// extractPlainText passes plain text and Markdown through verbatim
+func extractPlainText(doc *FetchedDocument, content *types.WebPageContent) error {
+	body, err := decodeText(doc, content)
+	if err != nil {
+		return err
+	}
+
+	content.Format = FormatText
+	if doc.MediaType == "text/markdown" || doc.MediaType == "text/x-markdown" {
+		content.Format = FormatMarkdown
+	}
+	content.Content = string(body)
+	return nil
+}
+
+// extractBinarySummary describes content that cannot be rendered as text
+func extractBinarySummary(doc *FetchedDocument, content *types.WebPageContent) error {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(doc.Body))
+
+	var summary strings.Builder
+	summary.WriteString("Binary content is not displayed.\n")
+	summary.WriteString(fmt.Sprintf("Media Type: %s\n", doc.MediaType))
+	if sniffed != "" && sniffed != doc.MediaType && sniffed != "application/octet-stream" {
+		summary.WriteString(fmt.Sprintf("Detected Type: %s\n", sniffed))
+	}
+	if doc.Truncated {
+		summary.WriteString(fmt.Sprintf("Size: more than %d bytes\n", len(doc.Body)))
+	} else {
+		summary.WriteString(fmt.Sprintf("Size: %d bytes\n", len(doc.Body)))
+	}
+	if name := path.Base(doc.URL.Path); name != "/" && name != "." {
+		summary.WriteString(fmt.Sprintf("File Name: %s\n", name))
+	}
+
+	content.Format = FormatBinary
+	content.Content = strings.TrimSpace(summary.String())
+	return nil
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"ez-web-search/pkg/types"
+)
+
+// csvPreviewRows is the number of data rows rendered in a CSV table preview
+const csvPreviewRows = 50
+
+// csvMaxCellLength limits the characters shown per CSV cell
+const csvMaxCellLength = 100
+
+// extractJSON pretty-prints a JSON document, or the values selected by the JSONPath query in
+// the fetch options. Malformed JSON is passed through as text unless a query was requested.
+func extractJSON(doc *FetchedDocument, content *types.WebPageContent) error {
+	body, err := decodeText(doc, content)
+	if err != nil {
+		return err
+	}
+
+	query := strings.TrimSpace(doc.Options.JSONPath)
+	if query == "" {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, bytes.TrimSpace(body), "", "  "); err != nil {
+			content.Format = FormatText
+			content.Content = string(body)
+			return nil
+		}
+		content.Format = FormatJSON
+		content.Content = indented.String()
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var root any
+	if err := decoder.Decode(&root); err != nil {
+		return fmt.Errorf("failed to parse JSON for json_path query: %w", err)
+	}
+
+	matches, definite, err := evaluateJSONPath(root, query)
+	if err != nil {
+		return err
+	}
+
+	// A definite path returns its value, anything else the list of matches
+	var result any = matches
+	switch {
+	case definite && len(matches) == 1:
+		result = matches[0]
+	case matches == nil:
+		result = []any{}
+	}
+	var output bytes.Buffer
+	encoder := json.NewEncoder(&output)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("failed to encode JSONPath result: %w", err)
+	}
+
+	content.Format = FormatJSON
+	content.Description = fmt.Sprintf("JSONPath %s matched %d value(s)", query, len(matches))
+	content.Content = strings.TrimSpace(output.String())
+	return nil
+}
+
+// extractXML parses an XML document and re-renders it indented, using the first <title>
+// element as the title. Malformed XML is passed through as text.
+func extractXML(doc *FetchedDocument, content *types.WebPageContent) error {
+	body, err := decodeText(doc, content)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := readXMLTokens(body)
+	if err != nil {
+		content.Format = FormatText
+		content.Content = string(body)
+		return nil
+	}
+
+	content.Format = FormatXML
+	content.Title = xmlTitle(tokens)
+	content.Content = renderXML(tokens)
+	return nil
+}
+
+// readXMLTokens reads all tokens of a document without resolving namespace prefixes
+func readXMLTokens(body []byte) ([]xml.Token, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	// The body has already been transcoded to UTF-8 whatever the declaration says
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var tokens []xml.Token
+	depth := 0
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XML: %w", err)
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+		tokens = append(tokens, xml.CopyToken(token))
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("failed to parse XML: unclosed elements")
+	}
+	return tokens, nil
+}
+
+// xmlTitle returns the text of the first title element
+func xmlTitle(tokens []xml.Token) string {
+	for i, token := range tokens {
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "title" || i+1 >= len(tokens) {
+			continue
+		}
+		if text, ok := tokens[i+1].(xml.CharData); ok {
+			return strings.TrimSpace(string(text))
+		}
+	}
+	return ""
+}
+
+// renderXML writes tokens as indented XML; elements holding only text stay on one line
+func renderXML(tokens []xml.Token) string {
+	var output strings.Builder
+	depth := 0
+	indent := func() {
+		output.WriteString(strings.Repeat("  ", depth))
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		switch token := tokens[i].(type) {
+		case xml.ProcInst:
+			// The original declaration may name an encoding the output no longer uses
+			if token.Target == "xml" {
+				continue
+			}
+			indent()
+			output.WriteString(fmt.Sprintf("<?%s %s?>\n", token.Target, token.Inst))
+		case xml.Directive:
+			indent()
+			output.WriteString(fmt.Sprintf("<!%s>\n", token))
+		case xml.Comment:
+			indent()
+			output.WriteString(fmt.Sprintf("<!--%s-->\n", token))
+		case xml.CharData:
+			if text := strings.TrimSpace(string(token)); text != "" {
+				indent()
+				xml.EscapeText(&output, []byte(text))
+				output.WriteString("\n")
+			}
+		case xml.StartElement:
+			indent()
+			output.WriteString(xmlStartTag(token))
+
+			// Collapse <a>text</a> and <a></a> onto one line
+			if i+1 < len(tokens) {
+				if _, ok := tokens[i+1].(xml.EndElement); ok {
+					output.WriteString(fmt.Sprintf("</%s>\n", xmlName(token.Name)))
+					i++
+					continue
+				}
+			}
+			if i+2 < len(tokens) {
+				text, isText := tokens[i+1].(xml.CharData)
+				_, isEnd := tokens[i+2].(xml.EndElement)
+				if isText && isEnd {
+					xml.EscapeText(&output, bytes.TrimSpace(text))
+					output.WriteString(fmt.Sprintf("</%s>\n", xmlName(token.Name)))
+					i += 2
+					continue
+				}
+			}
+			output.WriteString("\n")
+			depth++
+		case xml.EndElement:
+			depth--
+			indent()
+			output.WriteString(fmt.Sprintf("</%s>\n", xmlName(token.Name)))
+		}
+	}
+	return strings.TrimSpace(output.String())
+}
+
+// xmlStartTag renders a start tag with its attributes
+func xmlStartTag(start xml.StartElement) string {
+	var tag strings.Builder
+	tag.WriteString("<")
+	tag.WriteString(xmlName(start.Name))
+	for _, attr := range start.Attr {
+		var value bytes.Buffer
+		xml.EscapeText(&value, []byte(attr.Value))
+		tag.WriteString(fmt.Sprintf(" %s=\"%s\"", xmlName(attr.Name), value.String()))
+	}
+	tag.WriteString(">")
+	return tag.String()
+}
+
+// xmlName renders a raw (unresolved) name with its namespace prefix
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// extractCSV renders the first rows of a CSV or TSV document as a Markdown table preview
+func extractCSV(doc *FetchedDocument, content *types.WebPageContent) error {
+	body, err := decodeText(doc, content)
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(body))
+	if doc.MediaType == "text/tab-separated-values" {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows [][]string
+	total, columns := 0, 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// A truncated body can end inside a record; keep what was read
+			if doc.Truncated || total > 0 {
+				break
+			}
+			content.Format = FormatText
+			content.Content = string(body)
+			return nil
+		}
+		total++
+		columns = max(columns, len(record))
+		if len(rows) <= csvPreviewRows {
+			rows = append(rows, record)
+		}
+	}
+
+	if total == 0 {
+		content.Format = FormatCSV
+		return nil
+	}
+
+	dataRows := total - 1
+	content.Format = FormatCSV
+	content.Description = fmt.Sprintf("%d rows, %d columns", dataRows, columns)
+	if dataRows > csvPreviewRows {
+		content.Description += fmt.Sprintf(" (showing the first %d rows)", csvPreviewRows)
+	}
+	if doc.Truncated {
+		content.Description += "; the body was truncated, so counts are lower bounds"
+	}
+	content.Content = renderMarkdownTable(rows, columns)
+	return nil
+}
+
+// renderMarkdownTable renders rows as a GFM table whose first row is the header
+func renderMarkdownTable(rows [][]string, columns int) string {
+	var table strings.Builder
+	writeRow := func(record []string) {
+		table.WriteString("|")
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(record) {
+				cell = markdownTableCell(record[i])
+			}
+			table.WriteString(" " + cell + " |")
+		}
+		table.WriteString("\n")
+	}
+
+	writeRow(rows[0])
+	table.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+	for _, record := range rows[1:] {
+		writeRow(record)
+	}
+	return strings.TrimSpace(table.String())
+}
+
+// markdownTableCell escapes a value for a single Markdown table cell
+func markdownTableCell(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if utf8.RuneCountInString(value) > csvMaxCellLength {
+		value = string([]rune(value)[:csvMaxCellLength]) + "…"
+	}
+	return strings.ReplaceAll(value, "|", "\\|")
+}
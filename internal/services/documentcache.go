@@ -0,0 +1,119 @@
+package services
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"ez-web-search/pkg/types"
+)
+
+// DocumentCacheHit is reported as the cache status of chunks served from the document cache
+const DocumentCacheHit = "DOCUMENT"
+
+// documentCacheEntry is an extracted page stored in the LRU list
+type documentCacheEntry struct {
+	key       string
+	content   *types.WebPageContent
+	expiresAt time.Time
+}
+
+// documentCache keeps recently extracted pages so that paginated reads do not re-download them
+type documentCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// newDocumentCache creates a document cache; a non-positive ttl or size disables it
+func newDocumentCache(ttl time.Duration, maxSize int) *documentCache {
+	return &documentCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get returns a copy of the cached document for key
+func (c *documentCache) Get(key string) (*types.WebPageContent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*documentCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+	content := *entry.content
+	return &content, true
+}
+
+// Put stores a copy of a fully extracted document
+func (c *documentCache) Put(key string, content *types.WebPageContent) {
+	if c.ttl <= 0 || c.maxSize <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := *content
+	entry := &documentCacheEntry{key: key, content: &stored, expiresAt: time.Now().Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*documentCacheEntry).key)
+	}
+}
+
+// documentCacheKey identifies an extracted document by URL and the options that change extraction
+func documentCacheKey(opts types.WebFetchOptions) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%t\x00%t\x00%s\x00%t\x00%s\x00%s\x00%s\x00%s\x00%t\x00%s",
+		opts.URL, opts.Format, opts.LinkStyle, opts.IncludeLinks, opts.IncludeImages, opts.JSONPath,
+		opts.IncludeStructuredData, opts.Selector, opts.XPath, opts.Extract, opts.Attribute, opts.AllMatches,
+		redirectOptionsKey(opts))
+}
+
+// paginateContent replaces content.Content with the chunk of at most maxLength characters
+// starting at startIndex and records the total length and the offset of the next chunk
+func paginateContent(content *types.WebPageContent, startIndex, maxLength int) {
+	runes := []rune(content.Content)
+	content.TotalLength = len(runes)
+
+	if startIndex < 0 {
+		startIndex = 0
+	}
+	if startIndex > len(runes) {
+		startIndex = len(runes)
+	}
+	end := len(runes)
+	if maxLength > 0 && startIndex+maxLength < end {
+		end = startIndex + maxLength
+	}
+
+	content.Content = string(runes[startIndex:end])
+	content.StartIndex = startIndex
+	content.NextIndex = 0
+	if end < len(runes) {
+		content.NextIndex = end
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"ez-web-search/pkg/types"
+)
+
+// maxQueryExclusions caps the number of -site: operators appended to a top-up query
+const maxQueryExclusions = 10
+
+// DomainFilter keeps or drops search results based on the domain of their link.
+// A domain matches itself and all of its subdomains.
+type DomainFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewDomainFilter creates a domain filter; an empty include list allows every domain not excluded
+func NewDomainFilter(include, exclude []string) *DomainFilter {
+	return &DomainFilter{
+		include: normalizeDomains(include),
+		exclude: normalizeDomains(exclude),
+	}
+}
+
+// Empty reports whether the filter has no rules
+func (f *DomainFilter) Empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// Allows reports whether a result link passes the filter
+func (f *DomainFilter) Allows(link string) bool {
+	host := hostOf(link)
+	if host == "" {
+		return false
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, domain := range f.exclude {
+		if domainMatches(host, domain) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, domain := range f.include {
+		if domainMatches(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the results that pass the filter and the number that were removed
+func (f *DomainFilter) Apply(results []types.SearchResult) ([]types.SearchResult, int) {
+	kept := make([]types.SearchResult, 0, len(results))
+	for _, result := range results {
+		if f.Allows(result.Link) {
+			kept = append(kept, result)
+		}
+	}
+	return kept, len(results) - len(kept)
+}
+
+// domainMatches reports whether host is domain or one of its subdomains
+func domainMatches(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// normalizeDomains lowercases domains and strips schemes, paths, site: prefixes and wildcard labels
+func normalizeDomains(domains []string) []string {
+	var normalized []string
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		domain = strings.TrimPrefix(domain, "site:")
+		if strings.Contains(domain, "://") {
+			if parsed, err := url.Parse(domain); err == nil {
+				domain = parsed.Hostname()
+			}
+		}
+		if i := strings.IndexAny(domain, "/:"); i >= 0 {
+			domain = domain[:i]
+		}
+		domain = strings.TrimPrefix(domain, "*.")
+		domain = strings.Trim(domain, ".")
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
+
+// searchFiltered runs a search, applies the domain filter and re-queries to top up
+// the result list when filtering leaves fewer than the configured minimum
+func (s *WebSearchService) searchFiltered(ctx context.Context, opts types.WebSearchOptions, filter *DomainFilter) (*types.SearchResponse, error) {
+	resp, err := s.search(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.SearchResult, resp.FilteredCount = filter.Apply(resp.SearchResult)
+
+	minResults := s.config.Search.MinResults
+	if opts.Count > 0 && opts.Count < minResults {
+		minResults = opts.Count
+	}
+
+	seen := make(map[string]bool)
+	for _, result := range resp.SearchResult {
+		seen[canonicalURL(result.Link)] = true
+	}
+
+	for _, topUpOpts := range s.topUpQueries(opts, filter) {
+		if len(resp.SearchResult) >= minResults || ctx.Err() != nil {
+			break
+		}
+
+		extra, err := s.search(ctx, topUpOpts)
+		if err != nil {
+			// A failed top-up should not discard the results we already have
+			continue
+		}
+
+		kept, removed := filter.Apply(extra.SearchResult)
+		resp.FilteredCount += removed
+		for _, result := range kept {
+			key := canonicalURL(result.Link)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			resp.SearchResult = append(resp.SearchResult, result)
+		}
+	}
+
+	if opts.Count > 0 && len(resp.SearchResult) > opts.Count {
+		resp.SearchResult = resp.SearchResult[:opts.Count]
+	}
+	return resp, nil
+}
+
+// topUpQueries builds follow-up searches that are more likely to survive the filter:
+// one site-restricted query per included domain, or a query excluding the blocked domains
+func (s *WebSearchService) topUpQueries(opts types.WebSearchOptions, filter *DomainFilter) []types.WebSearchOptions {
+	var queries []types.WebSearchOptions
+
+	if len(filter.include) > 0 && opts.DomainFilter == "" {
+		for _, domain := range filter.include {
+			topUp := opts
+			topUp.DomainFilter = domain
+			queries = append(queries, topUp)
+		}
+	} else if len(filter.exclude) > 0 {
+		// Keep the query a reasonable length when the global blocklist is large
+		var exclusions []string
+		for _, domain := range filter.exclude {
+			if len(exclusions) == maxQueryExclusions {
+				break
+			}
+			exclusions = append(exclusions, fmt.Sprintf("-site:%s", domain))
+		}
+		// The top-up asks for the requested count like the original query; the results it
+		// adds beyond that count are trimmed afterwards
+		topUp := opts
+		topUp.Query = opts.Query + " " + strings.Join(exclusions, " ")
+		queries = append(queries, topUp)
+	}
+
+	if limit := max(s.config.Search.TopUpAttempts, 0); len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return queries
+}
@@ -0,0 +1,569 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"ez-web-search/internal/utils"
+	"ez-web-search/pkg/types"
+)
+
+// Feed formats reported in types.Feed
+const (
+	FeedFormatRSS  = "rss"
+	FeedFormatAtom = "atom"
+	FeedFormatJSON = "json"
+)
+
+// Entry limits for ez_feed_read
+const (
+	DefaultFeedLimit = 20
+	MaxFeedLimit     = 100
+)
+
+// feedSummaryLength is the maximum length of an entry summary in characters
+const feedSummaryLength = 500
+
+// feedMediaTypes are the <link rel="alternate"> types recognized during feed auto-discovery.
+// Generic JSON and XML types are left out, since sites also advertise APIs with them.
+var feedMediaTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/rdf+xml":   true,
+}
+
+// feedDateLayouts are the date formats found in RSS, Atom and JSON feeds
+var feedDateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 02 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// FeedService reads RSS, Atom and JSON feeds through the web fetch HTTP stack
+type FeedService struct {
+	fetcher *WebFetchService
+}
+
+// NewFeedService creates a feed service that downloads feeds with fetcher
+func NewFeedService(fetcher *WebFetchService) *FeedService {
+	return &FeedService{fetcher: fetcher}
+}
+
+// ReadFeed fetches and parses a feed. If the URL points to an HTML page, the first feed it
+// advertises through <link rel="alternate"> is read instead.
+func (s *FeedService) ReadFeed(ctx context.Context, opts types.FeedOptions) (*types.Feed, error) {
+	fetched, err := s.fetch(ctx, opts.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var sourceURL string
+	var discovered []string
+	if fetched.MediaType == "text/html" || fetched.MediaType == "application/xhtml+xml" {
+		discovered, err = discoverFeeds(fetched)
+		if err != nil {
+			return nil, err
+		}
+		if len(discovered) == 0 {
+			return nil, fmt.Errorf("no feed found: %s is an HTML page without <link rel=\"alternate\"> feed links", opts.URL)
+		}
+
+		sourceURL = opts.URL
+		fetched, err = s.fetch(ctx, discovered[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch discovered feed %s: %w", discovered[0], err)
+		}
+	}
+
+	feed, err := parseFeed(fetched)
+	if err != nil {
+		return nil, err
+	}
+	feed.SourceURL = sourceURL
+	feed.DiscoveredFeeds = discovered
+	feed.TotalEntries = len(feed.Entries)
+	feed.Entries = filterFeedEntries(feed.Entries, opts.Since, opts.Limit)
+	return feed, nil
+}
+
+// fetch downloads a feed or page and rejects error responses
+func (s *FeedService) fetch(ctx context.Context, feedURL string) (*FetchedDocument, error) {
+	fetched, err := s.fetcher.Fetch(ctx, types.WebFetchOptions{URL: feedURL})
+	if err != nil {
+		return nil, err
+	}
+	if fetched.StatusCode >= 400 {
+		return nil, fmt.Errorf("feed request failed with status %d", fetched.StatusCode)
+	}
+	return fetched, nil
+}
+
+// discoverFeeds returns the absolute URLs of the feeds advertised by an HTML page
+func discoverFeeds(fetched *FetchedDocument) ([]string, error) {
+	body, err := utils.ToUTF8(fetched.Body, utils.DetectCharset(fetched.Body, fetched.ContentType))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	var feeds []string
+	seen := make(map[string]bool)
+	doc.Find("link[rel][href]").Each(func(i int, link *goquery.Selection) {
+		rels := strings.Fields(strings.ToLower(link.AttrOr("rel", "")))
+		mediaType := strings.ToLower(strings.TrimSpace(link.AttrOr("type", "")))
+		if !slices.Contains(rels, "alternate") || !feedMediaTypes[mediaType] {
+			return
+		}
+		absolute, err := fetched.URL.Parse(strings.TrimSpace(link.AttrOr("href", "")))
+		if err != nil || seen[absolute.String()] {
+			return
+		}
+		seen[absolute.String()] = true
+		feeds = append(feeds, absolute.String())
+	})
+	return feeds, nil
+}
+
+// parseFeed detects the feed format and parses it into a normalized feed
+func parseFeed(fetched *FetchedDocument) (*types.Feed, error) {
+	body, err := utils.ToUTF8(fetched.Body, utils.DetectCharset(fetched.Body, fetched.ContentType))
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.TrimSpace(body)
+
+	var feed *types.Feed
+	if bytes.HasPrefix(body, []byte("{")) {
+		feed, err = parseJSONFeed(body, fetched.URL)
+	} else {
+		feed, err = parseXMLFeed(body, fetched.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	feed.URL = fetched.URL.String()
+	return feed, nil
+}
+
+// xmlFeed covers RSS 2.0, RSS 1.0 (RDF) and Atom 1.0 documents; element names are matched
+// without namespaces so that prefixed variants are accepted
+type xmlFeed struct {
+	XMLName xml.Name
+	// RSS 2.0 wraps everything in <channel>; RSS 1.0 puts items next to it
+	Channel *xmlFeedChannel `xml:"channel"`
+	Items   []xmlFeedEntry  `xml:"item"`
+	// Atom
+	Title    xmlText        `xml:"title"`
+	Subtitle xmlText        `xml:"subtitle"`
+	Links    []xmlFeedLink  `xml:"link"`
+	Entries  []xmlFeedEntry `xml:"entry"`
+}
+
+// xmlFeedChannel is the RSS <channel> element
+type xmlFeedChannel struct {
+	Title       string         `xml:"title"`
+	Link        []xmlFeedLink  `xml:"link"`
+	Description string         `xml:"description"`
+	Items       []xmlFeedEntry `xml:"item"`
+}
+
+// xmlFeedEntry is an RSS <item> or Atom <entry>
+type xmlFeedEntry struct {
+	Title       xmlText         `xml:"title"`
+	Links       []xmlFeedLink   `xml:"link"`
+	GUID        string          `xml:"guid"`
+	ID          string          `xml:"id"`
+	Description string          `xml:"description"`
+	Encoded     string          `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Summary     xmlText         `xml:"summary"`
+	Content     xmlText         `xml:"http://www.w3.org/2005/Atom content"`
+	PubDate     string          `xml:"pubDate"`
+	Date        string          `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Published   string          `xml:"published"`
+	Updated     string          `xml:"updated"`
+	Author      []xmlFeedAuthor `xml:"author"`
+	Creator     string          `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+// xmlFeedLink is an RSS <link>text</link> or an Atom <link rel="..." href="..."/>
+type xmlFeedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+// xmlFeedAuthor is an RSS author e-mail or an Atom <author><name>
+type xmlFeedAuthor struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+	Text  string `xml:",chardata"`
+}
+
+// xmlText is an Atom text construct, which may hold escaped or inline XHTML markup
+type xmlText struct {
+	Type  string `xml:"type,attr"`
+	Inner string `xml:",innerxml"`
+}
+
+// String returns the text content of the construct
+func (t xmlText) String() string {
+	inner := strings.TrimSpace(t.Inner)
+	if t.Type == "xhtml" || strings.HasPrefix(inner, "<") && !strings.HasPrefix(inner, "<![CDATA[") {
+		return inner
+	}
+	// Escaped text and CDATA sections are unescaped by decoding them as character data
+	var text struct {
+		Value string `xml:",chardata"`
+	}
+	if err := xml.Unmarshal([]byte("<t>"+inner+"</t>"), &text); err != nil {
+		return inner
+	}
+	return text.Value
+}
+
+// parseXMLFeed parses RSS and Atom documents
+func parseXMLFeed(body []byte, baseURL *url.URL) (*types.Feed, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	// The body has already been transcoded to UTF-8 whatever the declaration says
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var parsed xmlFeed
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	switch strings.ToLower(parsed.XMLName.Local) {
+	case "rss", "rdf":
+		feed := &types.Feed{Format: FeedFormatRSS}
+		items := parsed.Items
+		if parsed.Channel != nil {
+			feed.Title = strings.TrimSpace(parsed.Channel.Title)
+			feed.Link = resolveFeedURL(baseURL, rssLink(parsed.Channel.Link))
+			feed.Description = summarizeFeedText(parsed.Channel.Description)
+			items = append(parsed.Channel.Items, items...)
+		}
+		for _, item := range items {
+			feed.Entries = append(feed.Entries, normalizeXMLEntry(item, baseURL))
+		}
+		return feed, nil
+	case "feed":
+		feed := &types.Feed{
+			Format:      FeedFormatAtom,
+			Title:       strings.TrimSpace(summarizeFeedText(parsed.Title.String())),
+			Link:        resolveFeedURL(baseURL, atomLink(parsed.Links)),
+			Description: summarizeFeedText(parsed.Subtitle.String()),
+		}
+		for _, entry := range parsed.Entries {
+			feed.Entries = append(feed.Entries, normalizeXMLEntry(entry, baseURL))
+		}
+		return feed, nil
+	default:
+		return nil, fmt.Errorf("not a feed: unexpected root element <%s>", parsed.XMLName.Local)
+	}
+}
+
+// normalizeXMLEntry converts an RSS item or Atom entry to a feed entry
+func normalizeXMLEntry(entry xmlFeedEntry, baseURL *url.URL) types.FeedEntry {
+	link := atomLink(entry.Links)
+	if link == "" {
+		link = rssLink(entry.Links)
+	}
+	if link == "" && strings.HasPrefix(entry.GUID, "http") {
+		link = entry.GUID
+	}
+
+	summary := entry.Description
+	for _, candidate := range []string{entry.Summary.String(), entry.Content.String(), entry.Encoded} {
+		if strings.TrimSpace(summary) != "" {
+			break
+		}
+		summary = candidate
+	}
+
+	var author string
+	for _, candidate := range entry.Author {
+		if author = firstNonEmpty(candidate.Name, candidate.Text, candidate.Email); author != "" {
+			break
+		}
+	}
+	if author == "" {
+		author = strings.TrimSpace(entry.Creator)
+	}
+
+	return types.FeedEntry{
+		ID:        strings.TrimSpace(firstNonEmpty(entry.ID, entry.GUID, link)),
+		Title:     summarizeFeedText(entry.Title.String()),
+		Link:      resolveFeedURL(baseURL, link),
+		Published: formatFeedDate(firstNonEmpty(entry.Published, entry.PubDate, entry.Date, entry.Updated)),
+		Summary:   summarizeFeedText(summary),
+		Author:    strings.TrimSpace(author),
+	}
+}
+
+// rssLink returns the text of the first RSS <link> element
+func rssLink(links []xmlFeedLink) string {
+	for _, link := range links {
+		if text := strings.TrimSpace(link.Text); text != "" && link.Href == "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// atomLink returns the href of the alternate Atom <link>, or of the first link without a rel
+func atomLink(links []xmlFeedLink) string {
+	for _, link := range links {
+		if link.Href != "" && (link.Rel == "" || link.Rel == "alternate") {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+// jsonFeed is a JSON Feed 1.0 or 1.1 document
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+// jsonFeedItem is a JSON Feed item
+type jsonFeedItem struct {
+	ID            any              `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	Summary       string           `json:"summary"`
+	ContentText   string           `json:"content_text"`
+	ContentHTML   string           `json:"content_html"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Author        *jsonFeedAuthor  `json:"author"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+}
+
+// jsonFeedAuthor is a JSON Feed author object
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// parseJSONFeed parses a JSON Feed document
+func parseJSONFeed(body []byte, baseURL *url.URL) (*types.Feed, error) {
+	var parsed jsonFeed
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON feed: %w", err)
+	}
+	if !strings.Contains(parsed.Version, "jsonfeed.org") {
+		return nil, fmt.Errorf("not a feed: JSON document without a JSON Feed version")
+	}
+
+	feed := &types.Feed{
+		Format:      FeedFormatJSON,
+		Title:       strings.TrimSpace(parsed.Title),
+		Link:        resolveFeedURL(baseURL, parsed.HomePageURL),
+		Description: summarizeFeedText(parsed.Description),
+	}
+	for _, item := range parsed.Items {
+		var author string
+		if len(item.Authors) > 0 {
+			author = item.Authors[0].Name
+		} else if item.Author != nil {
+			author = item.Author.Name
+		}
+
+		link := firstNonEmpty(item.URL, item.ExternalURL)
+		id := ""
+		if item.ID != nil {
+			id = fmt.Sprint(item.ID)
+		}
+		feed.Entries = append(feed.Entries, types.FeedEntry{
+			ID:        firstNonEmpty(id, link),
+			Title:     strings.TrimSpace(item.Title),
+			Link:      resolveFeedURL(baseURL, link),
+			Published: formatFeedDate(firstNonEmpty(item.DatePublished, item.DateModified)),
+			Summary:   summarizeFeedText(firstNonEmpty(item.Summary, item.ContentText, item.ContentHTML)),
+			Author:    strings.TrimSpace(author),
+		})
+	}
+	return feed, nil
+}
+
+// filterFeedEntries drops entries published before since, orders the rest newest first
+// (undated entries last) and applies the limit
+func filterFeedEntries(entries []types.FeedEntry, since time.Time, limit int) []types.FeedEntry {
+	filtered := make([]types.FeedEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !since.IsZero() {
+			published, err := time.Parse(time.RFC3339, entry.Published)
+			if err != nil || published.Before(since) {
+				continue
+			}
+		}
+		filtered = append(filtered, entry)
+	}
+
+	// RFC 3339 timestamps in UTC sort lexically
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Published > filtered[j].Published
+	})
+
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+	return filtered
+}
+
+// parseFeedDate parses the date formats used by feeds
+func parseFeedDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range feedDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatFeedDate normalizes a feed date to RFC 3339 in UTC, or returns "" if it cannot be parsed
+func formatFeedDate(value string) string {
+	parsed, ok := parseFeedDate(value)
+	if !ok {
+		return ""
+	}
+	return parsed.UTC().Format(time.RFC3339)
+}
+
+// ParseFeedSince parses the since filter of ez_feed_read: an RFC 3339 timestamp, a date
+// (YYYY-MM-DD) or a duration relative to now such as 24h or 7d
+func ParseFeedSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if parsed, ok := parseFeedDate(value); ok {
+		return parsed, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return now.Add(-duration), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since value %q: use an RFC 3339 timestamp, a date (YYYY-MM-DD) or a duration such as 24h or 7d", value)
+}
+
+// summarizeFeedText converts HTML or plain text to a single whitespace-normalized line of at
+// most feedSummaryLength characters
+func summarizeFeedText(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.ContainsAny(value, "<&") {
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(value)); err == nil {
+			value = doc.Text()
+		}
+	}
+	value = strings.Join(strings.Fields(value), " ")
+	if utf8.RuneCountInString(value) > feedSummaryLength {
+		value = strings.TrimSpace(string([]rune(value)[:feedSummaryLength])) + "…"
+	}
+	return value
+}
+
+// resolveFeedURL resolves a possibly relative feed link against the feed URL
+func resolveFeedURL(baseURL *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+	if resolved, err := baseURL.Parse(link); err == nil {
+		return resolved.String()
+	}
+	return link
+}
+
+// firstNonEmpty returns the first value that is not blank
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// FormatFeed formats a feed for display
+func (s *FeedService) FormatFeed(feed *types.Feed) string {
+	var resultText string
+	resultText += fmt.Sprintf("Feed: %s\n", feed.URL)
+	if feed.SourceURL != "" {
+		resultText += fmt.Sprintf("Discovered From: %s\n", feed.SourceURL)
+		if len(feed.DiscoveredFeeds) > 1 {
+			resultText += fmt.Sprintf("Other Feeds: %s\n", strings.Join(feed.DiscoveredFeeds[1:], ", "))
+		}
+	}
+	resultText += fmt.Sprintf("Format: %s\n", feed.Format)
+	if feed.Title != "" {
+		resultText += fmt.Sprintf("Title: %s\n", feed.Title)
+	}
+	if feed.Link != "" {
+		resultText += fmt.Sprintf("Link: %s\n", feed.Link)
+	}
+	if feed.Description != "" {
+		resultText += fmt.Sprintf("Description: %s\n", feed.Description)
+	}
+	resultText += fmt.Sprintf("Entries: %d of %d\n\n", len(feed.Entries), feed.TotalEntries)
+
+	for i, entry := range feed.Entries {
+		resultText += fmt.Sprintf("%d. %s\n", i+1, entry.Title)
+		if entry.Link != "" {
+			resultText += fmt.Sprintf("   Link: %s\n", entry.Link)
+		}
+		if entry.Published != "" {
+			resultText += fmt.Sprintf("   Published: %s\n", entry.Published)
+		}
+		if entry.Author != "" {
+			resultText += fmt.Sprintf("   Author: %s\n", entry.Author)
+		}
+		if entry.Summary != "" {
+			resultText += fmt.Sprintf("   Summary: %s\n", entry.Summary)
+		}
+		resultText += "\n"
+	}
+
+	return resultText
+}
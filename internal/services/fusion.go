@@ -0,0 +1,159 @@
+package services
+
+import (
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"ez-web-search/pkg/types"
+)
+
+// trackingParams lists query parameters that never change the identity of a page
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"msclkid": true,
+	"spm":     true,
+	"ref_src": true,
+}
+
+// fusedResult accumulates a single result across several provider rankings
+type fusedResult struct {
+	result    types.SearchResult
+	score     float64
+	firstSeen int
+}
+
+// fuseResults merges provider rankings with reciprocal-rank fusion and deduplicates by canonical URL.
+// Responses are expected in a stable order so that ties are broken deterministically.
+func fuseResults(responses []*types.SearchResponse, k int, maxResults int) []types.SearchResult {
+	if k <= 0 {
+		k = 60
+	}
+
+	merged := make(map[string]*fusedResult)
+	var order []string
+
+	for _, resp := range responses {
+		for rank, result := range resp.SearchResult {
+			key := canonicalURL(result.Link)
+			if key == "" {
+				continue
+			}
+
+			entry, exists := merged[key]
+			if !exists {
+				entry = &fusedResult{result: result, firstSeen: len(order)}
+				entry.result.Engines = nil
+				merged[key] = entry
+				order = append(order, key)
+			} else {
+				mergeResultFields(&entry.result, result)
+			}
+
+			// A provider may list the same page twice; only its best rank counts
+			if slices.Contains(entry.result.Engines, resp.Provider) {
+				continue
+			}
+			entry.result.Engines = append(entry.result.Engines, resp.Provider)
+			entry.score += 1.0 / float64(k+rank+1)
+		}
+	}
+
+	fused := make([]*fusedResult, 0, len(order))
+	for _, key := range order {
+		fused = append(fused, merged[key])
+	}
+	sort.SliceStable(fused, func(i, j int) bool {
+		if fused[i].score != fused[j].score {
+			return fused[i].score > fused[j].score
+		}
+		return fused[i].firstSeen < fused[j].firstSeen
+	})
+
+	if maxResults > 0 && len(fused) > maxResults {
+		fused = fused[:maxResults]
+	}
+
+	results := make([]types.SearchResult, 0, len(fused))
+	for _, entry := range fused {
+		entry.result.Score = entry.score
+		results = append(results, entry.result)
+	}
+	return results
+}
+
+// mergeResultFields fills empty fields of dst with values from src
+func mergeResultFields(dst *types.SearchResult, src types.SearchResult) {
+	if dst.Title == "" {
+		dst.Title = src.Title
+	}
+	if len(src.Content) > len(dst.Content) {
+		dst.Content = src.Content
+	}
+	if dst.Media == "" {
+		dst.Media = src.Media
+	}
+	if dst.Icon == "" {
+		dst.Icon = src.Icon
+	}
+	if dst.Refer == "" {
+		dst.Refer = src.Refer
+	}
+	if dst.PublishDate == "" {
+		dst.PublishDate = src.PublishDate
+	}
+}
+
+// mergeSearchIntents concatenates intents from several responses, dropping duplicate queries
+func mergeSearchIntents(responses []*types.SearchResponse) []types.SearchIntent {
+	var intents []types.SearchIntent
+	seen := make(map[string]bool)
+	for _, resp := range responses {
+		for _, intent := range resp.SearchIntent {
+			if seen[intent.Query] {
+				continue
+			}
+			seen[intent.Query] = true
+			intents = append(intents, intent)
+		}
+	}
+	return intents
+}
+
+// canonicalURL normalizes a result link so that trivially different URLs of the same page compare equal
+func canonicalURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return strings.ToLower(rawURL)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := parsed.Query()
+	for param := range query {
+		lower := strings.ToLower(param)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			query.Del(param)
+		}
+	}
+
+	path := strings.TrimRight(parsed.EscapedPath(), "/")
+
+	// Treat http and https as the same page; query.Encode sorts the parameters
+	canonical := host + path
+	if encoded := query.Encode(); encoded != "" {
+		canonical += "?" + encoded
+	}
+	return canonical
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"ez-web-search/internal/config"
+)
+
+// ProviderHealthStatus is a point-in-time view of a provider's recent reliability
+type ProviderHealthStatus struct {
+	Provider      string
+	ErrorRate     float64
+	Samples       int
+	LastFailure   time.Time
+	LastError     string
+	CooldownUntil time.Time
+}
+
+// Healthy reports whether the provider is outside its cooldown period at the given time
+func (h ProviderHealthStatus) Healthy(now time.Time) bool {
+	return !now.Before(h.CooldownUntil)
+}
+
+// providerHealth tracks the rolling outcomes of a single provider
+type providerHealth struct {
+	outcomes      []bool // true for failure, oldest first
+	lastFailure   time.Time
+	lastError     string
+	cooldownUntil time.Time
+}
+
+// HealthTracker records per-provider outcomes and puts unreliable providers into cooldown
+type HealthTracker struct {
+	mu        sync.Mutex
+	config    config.ProviderHealthConfig
+	providers map[string]*providerHealth
+	now       func() time.Time
+}
+
+// NewHealthTracker creates a health tracker with the given thresholds
+func NewHealthTracker(cfg config.ProviderHealthConfig) *HealthTracker {
+	if cfg.Window <= 0 {
+		cfg.Window = 20
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 1
+	}
+	return &HealthTracker{
+		config:    cfg,
+		providers: make(map[string]*providerHealth),
+		now:       time.Now,
+	}
+}
+
+// RecordSuccess records a successful call to provider
+func (t *HealthTracker) RecordSuccess(provider string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.record(provider, false)
+}
+
+// RecordFailure records a failed call to provider and starts a cooldown once the error rate is too high
+func (t *HealthTracker) RecordFailure(provider string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	health := t.record(provider, true)
+	health.lastFailure = t.now()
+	if err != nil {
+		health.lastError = err.Error()
+	}
+
+	if len(health.outcomes) >= t.config.MinSamples && errorRate(health.outcomes) >= t.config.ErrorThreshold {
+		health.cooldownUntil = health.lastFailure.Add(t.config.Cooldown)
+	}
+}
+
+// Available reports whether provider may be called, i.e. it is not cooling down
+func (t *HealthTracker) Available(provider string) bool {
+	return t.Status(provider).Healthy(t.now())
+}
+
+// Status returns the current health of provider
+func (t *HealthTracker) Status(provider string) ProviderHealthStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := ProviderHealthStatus{Provider: provider}
+	health, exists := t.providers[provider]
+	if !exists {
+		return status
+	}
+
+	status.ErrorRate = errorRate(health.outcomes)
+	status.Samples = len(health.outcomes)
+	status.LastFailure = health.lastFailure
+	status.LastError = health.lastError
+	status.CooldownUntil = health.cooldownUntil
+	return status
+}
+
+// record appends an outcome to the provider's rolling window; the caller must hold t.mu
+func (t *HealthTracker) record(provider string, failed bool) *providerHealth {
+	health, exists := t.providers[provider]
+	if !exists {
+		health = &providerHealth{}
+		t.providers[provider] = health
+	}
+
+	health.outcomes = append(health.outcomes, failed)
+	if len(health.outcomes) > t.config.Window {
+		health.outcomes = health.outcomes[len(health.outcomes)-t.config.Window:]
+	}
+	return health
+}
+
+// errorRate returns the fraction of failures in outcomes
+func errorRate(outcomes []bool) float64 {
+	if len(outcomes) == 0 {
+		return 0
+	}
+	failures := 0
+	for _, failed := range outcomes {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(outcomes))
+}
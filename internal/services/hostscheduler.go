@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxScheduledHosts bounds the idle hosts tracked before stale ones are dropped
+const maxScheduledHosts = 4096
+
+// hostScheduler caps the requests in flight overall and per host, and spaces out the requests
+// to the same host. Requests to different hosts never wait for each other beyond the global cap.
+type hostScheduler struct {
+	mu      sync.Mutex
+	hosts   map[string]*hostState
+	global  chan struct{}
+	perHost int
+}
+
+// hostState tracks the requests to one host
+type hostState struct {
+	slots chan struct{}
+	// next is the earliest time the next request to the host may start
+	next time.Time
+	// users counts the requests holding or waiting for this state
+	users int
+}
+
+// newHostScheduler creates a scheduler allowing maxConcurrent requests in total and maxPerHost
+// requests to each host at once; a non-positive limit removes that cap
+func newHostScheduler(maxConcurrent, maxPerHost int) *hostScheduler {
+	h := &hostScheduler{hosts: make(map[string]*hostState), perHost: maxPerHost}
+	if maxConcurrent > 0 {
+		h.global = make(chan struct{}, maxConcurrent)
+	}
+	return h
+}
+
+// Acquire waits for a free connection to host, then until interval has passed since the previous
+// request to it started, then for a free slot under the global cap. The returned function
+// releases the slots once the request has finished. Every wait ends early when ctx is done.
+func (h *hostScheduler) Acquire(ctx context.Context, host string, interval, maxWait time.Duration) (func(), error) {
+	state := h.state(host)
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+		case <-ctx.Done():
+			h.done(host, state)
+			return nil, fmt.Errorf("waiting for a free connection to %s: %w", host, ctx.Err())
+		}
+	}
+	release := func() {
+		if state.slots != nil {
+			<-state.slots
+		}
+		h.done(host, state)
+	}
+
+	if err := h.waitInterval(ctx, host, state, interval, maxWait); err != nil {
+		release()
+		return nil, err
+	}
+
+	if h.global != nil {
+		select {
+		case h.global <- struct{}{}:
+		case <-ctx.Done():
+			release()
+			return nil, fmt.Errorf("waiting for a free connection: %w", ctx.Err())
+		}
+		releaseHost := release
+		release = func() {
+			<-h.global
+			releaseHost()
+		}
+	}
+	return release, nil
+}
+
+// Wait only spaces out requests to host, for requests that already hold a connection slot
+// such as redirect hops
+func (h *hostScheduler) Wait(ctx context.Context, host string, interval, maxWait time.Duration) error {
+	state := h.state(host)
+	defer h.done(host, state)
+	return h.waitInterval(ctx, host, state, interval, maxWait)
+}
+
+// waitInterval reserves the next start time of host and sleeps until it. Requests that would
+// have to wait longer than maxWait fail instead of reserving a slot.
+func (h *hostScheduler) waitInterval(ctx context.Context, host string, state *hostState, interval, maxWait time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	start := now
+	if state.next.After(now) {
+		start = state.next
+	}
+	wait := start.Sub(now)
+	if maxWait > 0 && wait > maxWait {
+		h.mu.Unlock()
+		return fmt.Errorf("%s asks for %v between requests; the next slot is %v away, more than the maximum wait of %v",
+			host, interval, wait.Round(time.Second), maxWait)
+	}
+	reserved := start.Add(interval)
+	state.next = reserved
+	h.mu.Unlock()
+
+	if err := sleepContext(ctx, wait); err != nil {
+		// Give the slot back unless a later request has already reserved the one after it
+		h.mu.Lock()
+		if state.next.Equal(reserved) {
+			state.next = start
+		}
+		h.mu.Unlock()
+		return fmt.Errorf("waiting to send the next request to %s: %w", host, err)
+	}
+	return nil
+}
+
+// state returns the state of host and registers the caller as one of its users
+func (h *hostScheduler) state(host string) *hostState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, ok := h.hosts[host]
+	if !ok {
+		if len(h.hosts) >= maxScheduledHosts {
+			h.pruneLocked()
+		}
+		state = &hostState{}
+		if h.perHost > 0 {
+			state.slots = make(chan struct{}, h.perHost)
+		}
+		h.hosts[host] = state
+	}
+	state.users++
+	return state
+}
+
+// done unregisters a user of the state of host and forgets the host once it is idle and its
+// interval has passed
+func (h *hostScheduler) done(host string, state *hostState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state.users--
+	if state.users == 0 && !state.next.After(time.Now()) {
+		delete(h.hosts, host)
+	}
+}
+
+// pruneLocked drops idle hosts whose interval has passed; h.mu must be held
+func (h *hostScheduler) pruneLocked() {
+	now := time.Now()
+	for host, state := range h.hosts {
+		if state.users == 0 && !state.next.After(now) {
+			delete(h.hosts, host)
+		}
+	}
+}
+
+// sleepContext sleeps for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHostSchedulerCancelledWaitReleasesSlot(t *testing.T) {
+	h := newHostScheduler(0, 0)
+	interval := 200 * time.Millisecond
+
+	// The first request starts at once and reserves the next start in one interval
+	if err := h.Wait(context.Background(), "example.com", interval, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Waiters that give up must not keep their reservations
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if err := h.Wait(ctx, "example.com", interval, 0); err == nil {
+			t.Fatalf("cancelled wait %d succeeded", i)
+		}
+		cancel()
+	}
+
+	start := time.Now()
+	if err := h.Wait(context.Background(), "example.com", interval, interval); err != nil {
+		t.Fatalf("wait after cancelled waiters = %v, want the slot one interval after the first request", err)
+	}
+	if waited := time.Since(start); waited > interval {
+		t.Errorf("waited %v, want at most %v", waited, interval)
+	}
+}
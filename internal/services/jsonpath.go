@@ -0,0 +1,227 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is one segment of a compiled JSONPath expression
+type jsonPathStep struct {
+	// recursive selects matching descendants at any depth (..)
+	recursive bool
+	// wildcard selects every member or element (* or [*])
+	wildcard bool
+	// names selects object members ('a' or .a; several in ['a','b'])
+	names []string
+	// indexes selects array elements; negative values count from the end
+	indexes []int
+	// slice selects an array range [start:end]
+	slice *[2]*int
+}
+
+// evaluateJSONPath applies a JSONPath expression to a decoded JSON value and returns the matches
+// and whether the expression is definite, i.e. selects at most one value.
+// Supported: $, .name, ['name'], [n], [-n], [a,b], [start:end], * and the recursive descent ..
+func evaluateJSONPath(root any, expression string) ([]any, bool, error) {
+	steps, err := compileJSONPath(expression)
+	if err != nil {
+		return nil, false, err
+	}
+
+	definite := true
+	for _, step := range steps {
+		if step.recursive || step.wildcard || step.slice != nil || len(step.names)+len(step.indexes) != 1 {
+			definite = false
+		}
+	}
+
+	current := []any{root}
+	for _, step := range steps {
+		var next []any
+		for _, value := range current {
+			if step.recursive {
+				for _, descendant := range jsonDescendants(value) {
+					next = append(next, step.apply(descendant)...)
+				}
+			} else {
+				next = append(next, step.apply(value)...)
+			}
+		}
+		current = next
+	}
+	return current, definite, nil
+}
+
+// compileJSONPath parses a JSONPath expression into steps
+func compileJSONPath(expression string) ([]jsonPathStep, error) {
+	original := strings.TrimSpace(expression)
+	if original == "" {
+		return nil, fmt.Errorf("empty JSONPath expression")
+	}
+	expression = strings.TrimPrefix(original, "$")
+
+	var steps []jsonPathStep
+	for i := 0; i < len(expression); {
+		var step jsonPathStep
+		switch {
+		case strings.HasPrefix(expression[i:], ".."):
+			step.recursive = true
+			i += 2
+		case expression[i] == '.':
+			i++
+		case expression[i] == '[':
+		default:
+			if i != 0 {
+				return nil, fmt.Errorf("invalid JSONPath expression %q", original)
+			}
+		}
+
+		if i < len(expression) && expression[i] == '[' {
+			end := strings.IndexByte(expression[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated bracket in JSONPath expression %q", original)
+			}
+			if err := step.parseBracket(expression[i+1 : i+end]); err != nil {
+				return nil, err
+			}
+			i += end + 1
+		} else {
+			end := i
+			for end < len(expression) && expression[end] != '.' && expression[end] != '[' {
+				end++
+			}
+			name := expression[i:end]
+			if name == "" {
+				return nil, fmt.Errorf("missing member name in JSONPath expression %q", original)
+			}
+			if name == "*" {
+				step.wildcard = true
+			} else {
+				step.names = []string{name}
+			}
+			i = end
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// parseBracket parses the contents of a [...] selector
+func (s *jsonPathStep) parseBracket(selector string) error {
+	selector = strings.TrimSpace(selector)
+	if selector == "*" {
+		s.wildcard = true
+		return nil
+	}
+
+	if strings.Contains(selector, ":") {
+		parts := strings.SplitN(selector, ":", 2)
+		var bounds [2]*int
+		for j, part := range parts {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("invalid slice bound %q in JSONPath expression", part)
+			}
+			bounds[j] = &n
+		}
+		s.slice = &bounds
+		return nil
+	}
+
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '\'' || part[0] == '"') && part[len(part)-1] == part[0] {
+			s.names = append(s.names, part[1:len(part)-1])
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return fmt.Errorf("invalid selector %q in JSONPath expression", part)
+		}
+		s.indexes = append(s.indexes, n)
+	}
+	return nil
+}
+
+// apply returns the children of value selected by the step
+func (s jsonPathStep) apply(value any) []any {
+	var matches []any
+	switch typed := value.(type) {
+	case map[string]any:
+		if s.wildcard {
+			keys := make([]string, 0, len(typed))
+			for key := range typed {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				matches = append(matches, typed[key])
+			}
+		}
+		for _, name := range s.names {
+			if member, ok := typed[name]; ok {
+				matches = append(matches, member)
+			}
+		}
+	case []any:
+		switch {
+		case s.wildcard:
+			matches = append(matches, typed...)
+		case s.slice != nil:
+			start, end := 0, len(typed)
+			if s.slice[0] != nil {
+				start = clampIndex(*s.slice[0], len(typed))
+			}
+			if s.slice[1] != nil {
+				end = clampIndex(*s.slice[1], len(typed))
+			}
+			if start < end {
+				matches = append(matches, typed[start:end]...)
+			}
+		default:
+			for _, index := range s.indexes {
+				if index < 0 {
+					index += len(typed)
+				}
+				if index >= 0 && index < len(typed) {
+					matches = append(matches, typed[index])
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// clampIndex resolves a possibly negative slice bound against length n
+func clampIndex(index, n int) int {
+	if index < 0 {
+		index += n
+	}
+	return max(0, min(index, n))
+}
+
+// jsonDescendants returns value and all nested values in document order
+func jsonDescendants(value any) []any {
+	result := []any{value}
+	switch typed := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result = append(result, jsonDescendants(typed[key])...)
+		}
+	case []any:
+		for _, element := range typed {
+			result = append(result, jsonDescendants(element)...)
+		}
+	}
+	return result
+}
@@ -0,0 +1,510 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	markdownWhitespace = regexp.MustCompile(`[ \t\r\n\f]+`)
+	markdownBlankLines = regexp.MustCompile(`\n{3,}`)
+	codeLanguageClass  = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([A-Za-z0-9_+#-]+)`)
+)
+
+// markdownSkipped lists elements whose content never belongs in the Markdown output
+var markdownSkipped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Canvas:   true,
+	atom.Iframe:   true,
+	atom.Head:     true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Input:    true,
+	atom.Textarea: true,
+}
+
+// markdownBlocks lists container elements rendered as separate blocks
+var markdownBlocks = map[atom.Atom]bool{
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Main:       true,
+	atom.Header:     true,
+	atom.Footer:     true,
+	atom.Aside:      true,
+	atom.Nav:        true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.Details:    true,
+	atom.Summary:    true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.Form:       true,
+	atom.Fieldset:   true,
+	atom.Address:    true,
+	atom.Body:       true,
+	atom.Html:       true,
+}
+
+// markdownConverter renders an HTML subtree as structure-preserving Markdown
+type markdownConverter struct {
+	baseURL    *url.URL
+	linkStyle  string
+	references []string
+	refIndex   map[string]int
+	codeBlocks []string
+}
+
+// htmlToMarkdown converts the given nodes to Markdown, resolving links against baseURL.
+// With LinkStyleReference links are numbered and listed as footnotes at the end.
+func htmlToMarkdown(nodes []*html.Node, baseURL *url.URL, linkStyle string) string {
+	c := &markdownConverter{
+		baseURL:   baseURL,
+		linkStyle: linkStyle,
+		refIndex:  make(map[string]int),
+	}
+
+	var out strings.Builder
+	for _, node := range nodes {
+		out.WriteString(c.block(c.node(node)))
+	}
+
+	markdown := c.finish(out.String())
+	if len(c.references) > 0 {
+		var refs strings.Builder
+		for i, ref := range c.references {
+			refs.WriteString(fmt.Sprintf("[%d]: %s\n", i+1, ref))
+		}
+		markdown += "\n\n" + strings.TrimSpace(refs.String())
+	}
+	return markdown
+}
+
+// finish collapses blank lines and restores fenced code blocks
+func (c *markdownConverter) finish(markdown string) string {
+	// Blank out whitespace-only lines and drop stray indentation at the start of a block,
+	// which inline text between block elements leaves behind
+	lines := strings.Split(markdown, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		} else if i == 0 || lines[i-1] == "" {
+			lines[i] = strings.TrimLeft(line, " ")
+		}
+	}
+	markdown = strings.Join(lines, "\n")
+	markdown = markdownBlankLines.ReplaceAllString(markdown, "\n\n")
+	markdown = strings.TrimSpace(markdown)
+
+	// Code blocks are kept out of whitespace normalization until the very end. A placeholder is
+	// a single line, so the list indentation or quote markers in front of it are repeated on
+	// every line of the restored block to keep it inside its container.
+	for i, code := range c.codeBlocks {
+		placeholder := codePlaceholder(i)
+		at := strings.Index(markdown, placeholder)
+		if at < 0 {
+			continue
+		}
+		lineStart := strings.LastIndex(markdown[:at], "\n") + 1
+		if prefix, ok := containerPrefix(markdown[lineStart:at]); ok && prefix != "" {
+			lines := strings.Split(code, "\n")
+			for j := 1; j < len(lines); j++ {
+				if lines[j] == "" {
+					lines[j] = strings.TrimRight(prefix, " ")
+				} else {
+					lines[j] = prefix + lines[j]
+				}
+			}
+			code = strings.Join(lines, "\n")
+		}
+		markdown = markdown[:at] + code + markdown[at+len(placeholder):]
+	}
+	return markdown
+}
+
+// containerPrefix turns the text in front of a code block on its line into the prefix of the
+// block's following lines: quote markers are kept and list markers become indentation.
+// It reports false when the text is not made of container markers only.
+func containerPrefix(prefix string) (string, bool) {
+	var out strings.Builder
+	for _, r := range prefix {
+		switch {
+		case r == '>' || r == ' ':
+			out.WriteRune(r)
+		case r == '-' || r == '*' || r == '+' || r == '.' || r == ')' || unicode.IsDigit(r):
+			out.WriteByte(' ')
+		default:
+			return "", false
+		}
+	}
+	return out.String(), true
+}
+
+// children renders the child nodes of n
+func (c *markdownConverter) children(n *html.Node) string {
+	var out strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		out.WriteString(c.node(child))
+	}
+	return out.String()
+}
+
+// node renders a single node
+func (c *markdownConverter) node(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return markdownWhitespace.ReplaceAllString(n.Data, " ")
+	case html.DocumentNode:
+		return c.children(n)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	if markdownSkipped[n.DataAtom] {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := singleLine(c.children(n))
+		if text == "" {
+			return ""
+		}
+		return c.block(strings.Repeat("#", level) + " " + text)
+	case atom.P:
+		return c.block(c.paragraph(c.children(n)))
+	case atom.Br:
+		return "\n"
+	case atom.Hr:
+		return c.block("---")
+	case atom.Strong, atom.B:
+		return wrapInline(c.children(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(c.children(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return wrapInline(c.children(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		return inlineCode(textContent(n))
+	case atom.Pre:
+		return c.codeBlock(n)
+	case atom.Blockquote:
+		return c.blockquote(n)
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Table:
+		return c.table(n)
+	case atom.A:
+		return c.link(n)
+	case atom.Img:
+		return c.image(n)
+	}
+
+	if markdownBlocks[n.DataAtom] {
+		return c.block(c.children(n))
+	}
+	return c.children(n)
+}
+
+// block surrounds content with blank lines
+func (c *markdownConverter) block(content string) string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
+	}
+	return "\n\n" + content + "\n\n"
+}
+
+// paragraph trims each line of inline content and joins explicit line breaks as hard breaks
+func (c *markdownConverter) paragraph(content string) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "  \n")
+}
+
+// codeBlock renders a pre element as a fenced code block
+func (c *markdownConverter) codeBlock(n *html.Node) string {
+	code := strings.Trim(textContent(n), "\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+
+	language := ""
+	for _, candidate := range append([]*html.Node{n}, childElements(n, atom.Code)...) {
+		if match := codeLanguageClass.FindStringSubmatch(attr(candidate, "class")); match != nil {
+			language = match[1]
+			break
+		}
+	}
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	c.codeBlocks = append(c.codeBlocks, fence+language+"\n"+code+"\n"+fence)
+	return c.block(codePlaceholder(len(c.codeBlocks) - 1))
+}
+
+// blockquote prefixes every line of the quoted content with "> "
+func (c *markdownConverter) blockquote(n *html.Node) string {
+	content := strings.TrimSpace(markdownBlankLines.ReplaceAllString(c.children(n), "\n\n"))
+	if content == "" {
+		return ""
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+strings.TrimSpace(line), " ")
+	}
+	return c.block(strings.Join(lines, "\n"))
+}
+
+// list renders ul and ol elements, indenting nested content under each item
+func (c *markdownConverter) list(n *html.Node) string {
+	ordered := n.DataAtom == atom.Ol
+	number := 1
+	if start := attr(n, "start"); start != "" {
+		fmt.Sscanf(start, "%d", &number)
+	}
+
+	var items []string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.DataAtom != atom.Li {
+			continue
+		}
+
+		content := strings.TrimSpace(markdownBlankLines.ReplaceAllString(c.children(child), "\n\n"))
+		content = strings.ReplaceAll(content, "\n\n", "\n")
+		if content == "" {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		indent := strings.Repeat(" ", len(marker))
+
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			if i == 0 {
+				lines[i] = marker + strings.TrimSpace(line)
+			} else if strings.TrimSpace(line) != "" {
+				lines[i] = indent + strings.TrimRight(line, " ")
+			}
+		}
+		items = append(items, strings.Join(lines, "\n"))
+	}
+
+	if len(items) == 0 {
+		return ""
+	}
+	return c.block(strings.Join(items, "\n"))
+}
+
+// table renders a table as a GitHub-flavored Markdown pipe table
+func (c *markdownConverter) table(n *html.Node) string {
+	var rows [][]string
+	var visit func(*html.Node)
+	visit = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Tr:
+				var cells []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						text := singleLine(c.children(cell))
+						cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				if len(cells) > 0 {
+					rows = append(rows, cells)
+				}
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				visit(child)
+			}
+		}
+	}
+	visit(n)
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	var out strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		out.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			out.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+
+	if caption := childElements(n, atom.Caption); len(caption) > 0 {
+		if text := singleLine(c.children(caption[0])); text != "" {
+			return c.block("**"+text+"**") + c.block(out.String())
+		}
+	}
+	return c.block(out.String())
+}
+
+// link renders an anchor inline or as a numbered reference
+func (c *markdownConverter) link(n *html.Node) string {
+	text := singleLine(c.children(n))
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return text
+	}
+
+	target := c.resolve(href)
+	if text == "" {
+		text = target
+	}
+
+	if c.linkStyle == LinkStyleReference {
+		index, exists := c.refIndex[target]
+		if !exists {
+			c.references = append(c.references, target)
+			index = len(c.references)
+			c.refIndex[target] = index
+		}
+		return fmt.Sprintf("[%s][%d]", text, index)
+	}
+	return fmt.Sprintf("[%s](%s)", text, target)
+}
+
+// image renders an img element
+func (c *markdownConverter) image(n *html.Node) string {
+	src := strings.TrimSpace(attr(n, "src"))
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return ""
+	}
+	alt := singleLine(attr(n, "alt"))
+	return fmt.Sprintf("![%s](%s)", alt, c.resolve(src))
+}
+
+// resolve converts a relative reference to an absolute URL
+func (c *markdownConverter) resolve(ref string) string {
+	if c.baseURL == nil {
+		return ref
+	}
+	if resolved, err := c.baseURL.Parse(ref); err == nil {
+		return resolved.String()
+	}
+	return ref
+}
+
+// codePlaceholder marks the position of a fenced code block during rendering
+func codePlaceholder(index int) string {
+	return fmt.Sprintf("\x00CODE%d\x00", index)
+}
+
+// wrapInline wraps trimmed inline content with a Markdown marker, keeping surrounding spaces outside
+func wrapInline(content, marker string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" || strings.Contains(trimmed, "\n") {
+		return content
+	}
+
+	prefix, suffix := "", ""
+	if strings.HasPrefix(content, " ") {
+		prefix = " "
+	}
+	if strings.HasSuffix(content, " ") {
+		suffix = " "
+	}
+	return prefix + marker + trimmed + marker + suffix
+}
+
+// inlineCode renders text as a code span, using a longer fence if the text contains backticks
+func inlineCode(text string) string {
+	text = markdownWhitespace.ReplaceAllString(text, " ")
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if len(fence) > 1 {
+		return fence + " " + text + " " + fence
+	}
+	return fence + text + fence
+}
+
+// singleLine collapses all whitespace, including line breaks, into single spaces
+func singleLine(content string) string {
+	return strings.TrimSpace(markdownWhitespace.ReplaceAllString(content, " "))
+}
+
+// textContent returns the raw text of a subtree, preserving whitespace
+func textContent(n *html.Node) string {
+	var out strings.Builder
+	var visit func(*html.Node)
+	visit = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			out.WriteString(node.Data)
+			return
+		}
+		if node.Type == html.ElementNode && node.DataAtom == atom.Br {
+			out.WriteString("\n")
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(n)
+	return out.String()
+}
+
+// childElements returns the direct children of n with the given tag
+func childElements(n *html.Node, tag atom.Atom) []*html.Node {
+	var elements []*html.Node
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == tag {
+			elements = append(elements, child)
+		}
+	}
+	return elements
+}
+
+// attr returns the value of an attribute, or an empty string
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
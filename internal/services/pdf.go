@@ -0,0 +1,219 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+
+	"ez-web-search/pkg/types"
+)
+
+// pdfDatePattern matches PDF dates such as D:20240131120000+08'00'
+var pdfDatePattern = regexp.MustCompile(`^(?:D:)?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?(Z|[+-]\d{2}'?\d{2}'?)?`)
+
+// extractPDFContent fills content with the metadata and per-page text of a PDF document
+func extractPDFContent(body []byte, content *types.WebPageContent) (err error) {
+	// The PDF reader panics on malformed input
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	info := reader.Trailer().Key("Info")
+	content.Title = strings.TrimSpace(info.Key("Title").Text())
+	content.Author = strings.TrimSpace(info.Key("Author").Text())
+	content.Description = strings.TrimSpace(info.Key("Subject").Text())
+	content.Keywords = strings.TrimSpace(info.Key("Keywords").Text())
+	if created, ok := parsePDFDate(info.Key("CreationDate").Text()); ok {
+		content.CreatedAt = created.Format(time.RFC3339)
+	}
+
+	content.PageCount = reader.NumPage()
+	content.Pages = make([]string, 0, content.PageCount)
+	for i := 1; i <= content.PageCount; i++ {
+		content.Pages = append(content.Pages, pdfPageText(reader.Page(i)))
+	}
+
+	if content.Format != FormatMarkdown {
+		content.Format = FormatText
+	}
+	content.Content = formatPDFPages(content.Pages, content.Format)
+	return nil
+}
+
+// pdfWordGap is the TJ positioning adjustment, in thousandths of an em, treated as a word break
+const pdfWordGap = -200
+
+// pdfPageText extracts the text of a page by interpreting its content stream. Line and word
+// breaks are inferred from text positioning operators rather than glyph widths, which keeps
+// extraction fast on documents whose fonts live in compressed object streams.
+func pdfPageText(page pdf.Page) (text string) {
+	if page.V.IsNull() {
+		return ""
+	}
+	// A single broken page should not lose the rest of the document
+	defer func() {
+		if r := recover(); r != nil {
+			text = ""
+		}
+	}()
+
+	encoders := make(map[string]pdf.TextEncoding)
+	for _, name := range page.Fonts() {
+		encoders[name] = page.Font(name).Encoder()
+	}
+
+	var builder strings.Builder
+	var encoder pdf.TextEncoding
+	var lineY float64
+	breakLine := func() { builder.WriteString("\n") }
+	breakWord := func() { builder.WriteString(" ") }
+	show := func(raw string) {
+		if encoder == nil {
+			builder.WriteString(raw)
+			return
+		}
+		builder.WriteString(encoder.Decode(raw))
+	}
+
+	interpret := func(stream pdf.Value) {
+		pdf.Interpret(stream, func(stk *pdf.Stack, op string) {
+			args := make([]pdf.Value, stk.Len())
+			for i := len(args) - 1; i >= 0; i-- {
+				args[i] = stk.Pop()
+			}
+
+			switch op {
+			case "Tf":
+				if len(args) == 2 {
+					encoder = encoders[args[0].Name()]
+				}
+			case "Td", "TD":
+				if len(args) == 2 {
+					if args[1].Float64() != 0 {
+						breakLine()
+					} else if args[0].Float64() != 0 {
+						breakWord()
+					}
+				}
+			case "Tm":
+				if len(args) == 6 {
+					if y := args[5].Float64(); y != lineY {
+						lineY = y
+						breakLine()
+					} else {
+						breakWord()
+					}
+				}
+			case "T*":
+				breakLine()
+			case "'", "\"":
+				breakLine()
+				if len(args) > 0 {
+					show(args[len(args)-1].RawString())
+				}
+			case "Tj":
+				if len(args) == 1 {
+					show(args[0].RawString())
+				}
+			case "TJ":
+				if len(args) == 1 {
+					for i := 0; i < args[0].Len(); i++ {
+						item := args[0].Index(i)
+						if item.Kind() == pdf.String {
+							show(item.RawString())
+						} else if item.Float64() < pdfWordGap {
+							breakWord()
+						}
+					}
+				}
+			}
+		})
+	}
+
+	contents := page.V.Key("Contents")
+	if contents.Kind() == pdf.Array {
+		for i := 0; i < contents.Len(); i++ {
+			interpret(contents.Index(i))
+		}
+	} else {
+		interpret(contents)
+	}
+
+	// Drop undecodable glyphs and control codes, normalize spacing and drop blank lines
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '\n' || r == ' ' {
+			return r
+		}
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, builder.String())
+
+	var lines []string
+	for _, line := range strings.Split(cleaned, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatPDFPages joins page texts with page markers suited to the output format
+func formatPDFPages(pages []string, format string) string {
+	var builder strings.Builder
+	for i, page := range pages {
+		if format == FormatMarkdown {
+			builder.WriteString(fmt.Sprintf("## Page %d\n\n", i+1))
+		} else {
+			builder.WriteString(fmt.Sprintf("--- Page %d ---\n", i+1))
+		}
+		builder.WriteString(page)
+		builder.WriteString("\n\n")
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// parsePDFDate parses a PDF date string (D:YYYYMMDDHHmmSSOHH'mm')
+func parsePDFDate(value string) (time.Time, bool) {
+	match := pdfDatePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	field := func(i, defaultValue int) int {
+		if match[i] == "" {
+			return defaultValue
+		}
+		var n int
+		fmt.Sscanf(match[i], "%d", &n)
+		return n
+	}
+
+	location := time.UTC
+	if zone := strings.ReplaceAll(match[7], "'", ""); zone != "" && zone != "Z" {
+		var hours, minutes int
+		fmt.Sscanf(zone[1:3], "%d", &hours)
+		fmt.Sscanf(zone[3:], "%d", &minutes)
+		offset := hours*3600 + minutes*60
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		location = time.FixedZone("", offset)
+	}
+
+	return time.Date(field(1, 0), time.Month(field(2, 1)), field(3, 1), field(4, 0), field(5, 0), field(6, 0), 0, location), true
+}
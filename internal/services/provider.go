@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+
+	"ez-web-search/pkg/types"
+)
+
+// SearchProvider is implemented by every search backend that can serve ez_web_search
+type SearchProvider interface {
+	// Name returns the unique name used to select the provider
+	Name() string
+	// Search executes the query and returns provider-neutral results
+	Search(ctx context.Context, opts types.WebSearchOptions) (*types.SearchResponse, error)
+}
+
+// ProviderError describes a failed upstream call made by a search provider
+type ProviderError struct {
+	Provider    string
+	StatusCode  int
+	RateLimited bool
+	Err         error
+}
+
+// Error implements the error interface
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// isFailoverError reports whether err is transient enough that another provider should be tried:
+// a 5xx response, a rate limit or a timeout
+func isFailoverError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		if providerErr.RateLimited || providerErr.StatusCode == http.StatusTooManyRequests || providerErr.StatusCode >= 500 {
+			return true
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// ProviderRegistry holds the named search providers available to the search service
+type ProviderRegistry struct {
+	mu        sync.RWMutex
+	providers map[string]SearchProvider
+}
+
+// NewProviderRegistry creates an empty provider registry
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{
+		providers: make(map[string]SearchProvider),
+	}
+}
+
+// Register adds a provider to the registry, replacing any provider with the same name
+func (r *ProviderRegistry) Register(provider SearchProvider) error {
+	if provider == nil {
+		return fmt.Errorf("provider must not be nil")
+	}
+	name := provider.Name()
+	if name == "" {
+		return fmt.Errorf("provider name must not be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = provider
+	return nil
+}
+
+// Get returns the provider registered under name
+func (r *ProviderRegistry) Get(name string) (SearchProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Names returns the sorted names of all registered providers
+func (r *ProviderRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,455 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// unlikelyCandidates matches class/id values of page chrome that is never main content
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs?|combx|comment|community|consent|cookie|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|modal|newsletter|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|popup|promo|share|subscribe|tweet|twitter|pagination|pager`)
+	// maybeCandidates rescues elements that match unlikelyCandidates but look like content containers
+	maybeCandidates = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|post|entry|story|text`)
+	// positiveWeight matches class/id values that suggest main content
+	positiveWeight = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story|prose|markdown|documentation|docs`)
+	// negativeWeight matches class/id values that suggest page chrome
+	negativeWeight = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|cookie|consent|footer|gdpr|footnote|masthead|media|meta|modal|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|nav|menu|breadcrumb|newsletter|subscribe|popup`)
+)
+
+// unlikelyRoles lists ARIA landmark roles of page chrome
+var unlikelyRoles = map[string]bool{
+	"navigation":    true,
+	"complementary": true,
+	"contentinfo":   true,
+	"banner":        true,
+	"menu":          true,
+	"menubar":       true,
+	"dialog":        true,
+	"alertdialog":   true,
+	"search":        true,
+}
+
+// chromeElements are removed before scoring regardless of their attributes
+var chromeElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Input:    true,
+	atom.Textarea: true,
+	atom.Svg:      true,
+	atom.Dialog:   true,
+}
+
+// scoredTags are the elements whose text is scored and propagated to their ancestors
+var scoredTags = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Td:         true,
+	atom.Blockquote: true,
+	atom.Li:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+}
+
+// blockChildTags mark a div as a container rather than a paragraph of text
+var blockChildTags = map[atom.Atom]bool{
+	atom.Blockquote: true, atom.Dl: true, atom.Div: true, atom.Ol: true, atom.P: true,
+	atom.Pre: true, atom.Table: true, atom.Ul: true, atom.Section: true, atom.Article: true,
+	atom.H1: true, atom.H2: true, atom.H3: true,
+}
+
+// readabilityCandidate accumulates the score of an element that may hold the main content
+type readabilityCandidate struct {
+	node  *html.Node
+	score float64
+}
+
+// extractReadableContent finds the main content of a document by scoring text density,
+// link density, class/id heuristics and paragraph counts. The document is cloned so
+// page chrome can be stripped without affecting metadata, link and image extraction.
+func extractReadableContent(doc *goquery.Document) *goquery.Selection {
+	clone := goquery.CloneDocument(doc)
+	body := clone.Find("body")
+	if body.Length() == 0 {
+		return clone.Selection
+	}
+
+	removeChrome(body.Nodes[0])
+
+	candidates := scoreParagraphs(body.Nodes[0])
+	top := topCandidate(body.Nodes[0], candidates)
+	if top == nil {
+		return body
+	}
+
+	nodes := collectSiblings(top, candidates)
+	for _, node := range nodes {
+		cleanConditionally(node)
+	}
+	return clone.FindNodes(nodes...)
+}
+
+// removeChrome strips navigation, footers, sidebars, cookie banners, comment sections and hidden elements
+func removeChrome(root *html.Node) {
+	var remove []*html.Node
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.CommentNode {
+				remove = append(remove, child)
+				continue
+			}
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if isChrome(child) {
+				remove = append(remove, child)
+				continue
+			}
+			visit(child)
+		}
+	}
+	visit(root)
+
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// isChrome reports whether an element is page chrome rather than content
+func isChrome(n *html.Node) bool {
+	if chromeElements[n.DataAtom] {
+		return true
+	}
+	if unlikelyRoles[strings.ToLower(attr(n, "role"))] {
+		return true
+	}
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" || strings.Contains(strings.ReplaceAll(attr(n, "style"), " ", ""), "display:none") {
+		return true
+	}
+
+	// Headers inside articles often hold the title, so only page-level headers are dropped
+	if n.DataAtom == atom.Header && !hasAncestor(n, atom.Article, atom.Main) {
+		return true
+	}
+
+	if n.DataAtom == atom.Body || n.DataAtom == atom.A || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	matchString := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(matchString) && !maybeCandidates.MatchString(matchString)
+}
+
+// scoreParagraphs scores every text block and propagates the score to its ancestors
+func scoreParagraphs(root *html.Node) map[*html.Node]*readabilityCandidate {
+	candidates := make(map[*html.Node]*readabilityCandidate)
+
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if scoredTags[child.DataAtom] || (child.DataAtom == atom.Div && !hasBlockChildren(child)) {
+				scoreParagraph(child, candidates)
+				// Nested scored elements are counted through their own parent chain
+				if child.DataAtom != atom.Li && child.DataAtom != atom.Td {
+					continue
+				}
+			}
+			visit(child)
+		}
+	}
+	visit(root)
+
+	// Scale each candidate by the share of its text that is not link text
+	for _, candidate := range candidates {
+		candidate.score *= 1 - linkDensity(candidate.node)
+	}
+	return candidates
+}
+
+// scoreParagraph adds the score of a single text block to its parent, grandparent and great-grandparent
+func scoreParagraph(n *html.Node, candidates map[*html.Node]*readabilityCandidate) {
+	text := normalizedText(n)
+	if len(text) < 25 {
+		return
+	}
+
+	// One point for the block itself, one per comma and up to three for its length
+	score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，"))
+	score += math.Min(math.Floor(float64(len(text))/100), 3)
+
+	ancestor := n.Parent
+	for level := 0; ancestor != nil && level < 3; level++ {
+		if ancestor.Type != html.ElementNode || ancestor.DataAtom == atom.Html {
+			break
+		}
+
+		candidate, exists := candidates[ancestor]
+		if !exists {
+			candidate = &readabilityCandidate{node: ancestor, score: initialScore(ancestor)}
+			candidates[ancestor] = candidate
+		}
+
+		switch level {
+		case 0:
+			candidate.score += score
+		case 1:
+			candidate.score += score / 2
+		default:
+			candidate.score += score / float64(level*3)
+		}
+		ancestor = ancestor.Parent
+	}
+}
+
+// initialScore seeds a candidate from its tag and class/id weight
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	if n.DataAtom == atom.Article || n.DataAtom == atom.Main || attr(n, "role") == "main" || attr(n, "itemprop") == "articleBody" {
+		score += 10
+	}
+	return score
+}
+
+// classWeight scores an element's class and id against the content and chrome patterns
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// topCandidate returns the highest-scoring candidate below root, preferring an ancestor that
+// collects several similarly scored blocks (e.g. an article split into sections).
+// Candidates are visited in document order so that the first of equally scored ones wins.
+func topCandidate(root *html.Node, candidates map[*html.Node]*readabilityCandidate) *readabilityCandidate {
+	var top *readabilityCandidate
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if candidate, ok := candidates[n]; ok && (top == nil || candidate.score > top.score) {
+			top = candidate
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(root)
+	if top == nil || top.score <= 0 {
+		return nil
+	}
+
+	// Walk up while the parent's score stays close to the top score
+	for parent := top.node.Parent; parent != nil && parent.DataAtom != atom.Body; parent = parent.Parent {
+		parentCandidate, ok := candidates[parent]
+		if !ok || parentCandidate.score < top.score*0.75 {
+			break
+		}
+		// Only promote when the parent holds substantially more content
+		if len(normalizedText(parent)) < len(normalizedText(top.node))*3/2 {
+			break
+		}
+		top = parentCandidate
+	}
+	return top
+}
+
+// collectSiblings returns the top candidate together with siblings that look like part of the same content
+func collectSiblings(top *readabilityCandidate, candidates map[*html.Node]*readabilityCandidate) []*html.Node {
+	if top.node.Parent == nil {
+		return []*html.Node{top.node}
+	}
+
+	threshold := math.Max(10, top.score*0.2)
+	topClass := attr(top.node, "class")
+
+	var nodes []*html.Node
+	for sibling := top.node.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling == top.node {
+			nodes = append(nodes, sibling)
+			continue
+		}
+
+		bonus := 0.0
+		if topClass != "" && attr(sibling, "class") == topClass {
+			bonus = top.score * 0.2
+		}
+		if candidate, ok := candidates[sibling]; ok && candidate.score+bonus >= threshold {
+			nodes = append(nodes, sibling)
+			continue
+		}
+
+		// Loose paragraphs next to the article body are usually part of it
+		if sibling.DataAtom == atom.P {
+			text := normalizedText(sibling)
+			density := linkDensity(sibling)
+			if (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.ContainsAny(text, ".。!?！？")) {
+				nodes = append(nodes, sibling)
+			}
+		}
+	}
+	return nodes
+}
+
+// cleanConditionally removes link lists, empty containers and low-content blocks from the selected content
+func cleanConditionally(root *html.Node) {
+	var remove []*html.Node
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Div, atom.Section, atom.Ul, atom.Ol, atom.Table:
+				if shouldDropBlock(child) {
+					remove = append(remove, child)
+					continue
+				}
+			}
+			visit(child)
+		}
+	}
+	visit(root)
+
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// shouldDropBlock reports whether a container is mostly links or chrome rather than content
+func shouldDropBlock(n *html.Node) bool {
+	// Code listings and data tables are kept even when they are short
+	if hasDescendant(n, atom.Pre) || (n.DataAtom == atom.Table && countDescendants(n, atom.Th) > 0) {
+		return false
+	}
+
+	text := normalizedText(n)
+	if classWeight(n) < 0 && len(text) < 500 {
+		return true
+	}
+
+	density := linkDensity(n)
+	paragraphs := countDescendants(n, atom.P)
+	images := countDescendants(n, atom.Img)
+
+	if text == "" && images == 0 {
+		return true
+	}
+	return density > 0.5 && paragraphs < 2
+}
+
+// linkDensity returns the fraction of an element's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	textLength := len(normalizedText(n))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	var visit func(*html.Node)
+	visit = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && child.DataAtom == atom.A {
+				// In-page anchors are navigation aids, not outbound links
+				if !strings.HasPrefix(attr(child, "href"), "#") {
+					linkLength += len(normalizedText(child))
+				}
+				continue
+			}
+			visit(child)
+		}
+	}
+	visit(n)
+	return float64(linkLength) / float64(textLength)
+}
+
+// hasBlockChildren reports whether an element contains block-level children
+func hasBlockChildren(n *html.Node) bool {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && blockChildTags[child.DataAtom] {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAncestor reports whether any ancestor of n has one of the given tags
+func hasAncestor(n *html.Node, tags ...atom.Atom) bool {
+	for parent := n.Parent; parent != nil; parent = parent.Parent {
+		for _, tag := range tags {
+			if parent.DataAtom == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasDescendant reports whether n contains an element with the given tag
+func hasDescendant(n *html.Node, tag atom.Atom) bool {
+	return countDescendants(n, tag) > 0
+}
+
+// countDescendants counts the elements with the given tag below n
+func countDescendants(n *html.Node, tag atom.Atom) int {
+	count := 0
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == tag {
+			count++
+		}
+		count += countDescendants(child, tag)
+	}
+	return count
+}
+
+// hasAttr reports whether an element carries the attribute, with or without a value
+func hasAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizedText returns the text of a subtree with whitespace collapsed
+func normalizedText(n *html.Node) string {
+	return singleLine(textContent(n))
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"ez-web-search/internal/policy"
+	"ez-web-search/pkg/types"
+)
+
+// ErrRedirectRefused is matched by errors.Is for every RedirectError
+var ErrRedirectRefused = errors.New("redirect refused")
+
+// RedirectError reports a redirect that the redirect policy does not allow
+type RedirectError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+// Error implements the error interface
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("refusing redirect from %s to %s: %s", e.From, e.To, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrRedirectRefused) match
+func (e *RedirectError) Is(target error) bool {
+	return target == ErrRedirectRefused
+}
+
+// redirectSettings is the redirect policy of a single fetch
+type redirectSettings struct {
+	maxRedirects    int
+	allowCrossHost  bool
+	refuseDowngrade bool
+}
+
+// redirectTracker carries the redirect policy of a fetch to checkRedirect and collects the hops
+type redirectTracker struct {
+	settings redirectSettings
+	hops     []types.RedirectHop
+}
+
+// redirectTrackerKey is the context key of the redirect tracker
+type redirectTrackerKey struct{}
+
+// withRedirectTracker returns a context whose requests record their redirects in tracker;
+// a nil tracker applies the configured policy without recording
+func withRedirectTracker(ctx context.Context, tracker *redirectTracker) context.Context {
+	return context.WithValue(ctx, redirectTrackerKey{}, tracker)
+}
+
+// redirectSettingsFor applies the configured defaults to the redirect options of a fetch
+func (s *WebFetchService) redirectSettingsFor(opts types.WebFetchOptions) redirectSettings {
+	settings := redirectSettings{
+		maxRedirects:    s.config.WebFetch.Redirects.MaxRedirects,
+		allowCrossHost:  s.config.WebFetch.Redirects.AllowCrossHost,
+		refuseDowngrade: s.config.WebFetch.Redirects.RefuseHTTPSDowngrade,
+	}
+	if opts.MaxRedirects != nil {
+		settings.maxRedirects = *opts.MaxRedirects
+	}
+	if opts.AllowCrossHostRedirects != nil {
+		settings.allowCrossHost = *opts.AllowCrossHostRedirects
+	}
+	if opts.RefuseHTTPSDowngrade != nil {
+		settings.refuseDowngrade = *opts.RefuseHTTPSDowngrade
+	}
+	return settings
+}
+
+// checkRedirect records every redirect hop and applies the redirect policy, the URL policy and,
+// in polite mode, robots.txt to it before spacing it out from earlier requests to its host
+func (s *WebFetchService) checkRedirect(req *http.Request, via []*http.Request) error {
+	tracker, _ := req.Context().Value(redirectTrackerKey{}).(*redirectTracker)
+	settings := s.redirectSettingsFor(types.WebFetchOptions{})
+	if tracker != nil {
+		settings = tracker.settings
+	}
+
+	previous := via[len(via)-1].URL
+	if tracker != nil && req.Response != nil {
+		tracker.hops = append(tracker.hops, types.RedirectHop{
+			URL:        previous.String(),
+			StatusCode: req.Response.StatusCode,
+			Location:   req.Response.Header.Get("Location"),
+		})
+	}
+
+	if len(via) > settings.maxRedirects {
+		return &RedirectError{From: previous.String(), To: req.URL.String(), Reason: fmt.Sprintf("redirect limit of %d reached", settings.maxRedirects)}
+	}
+	if !settings.allowCrossHost && !strings.EqualFold(via[0].URL.Hostname(), req.URL.Hostname()) {
+		return &RedirectError{From: previous.String(), To: req.URL.String(), Reason: "cross-host redirects are not allowed"}
+	}
+	if settings.refuseDowngrade && previous.Scheme == "https" && req.URL.Scheme == "http" {
+		return &RedirectError{From: previous.String(), To: req.URL.String(), Reason: "downgrade from https to http"}
+	}
+
+	if err := s.policy.Check(req.URL, policy.Confirmed(req.Context(), req.URL)); err != nil {
+		return err
+	}
+	crawlDelay, err := s.checkRobots(req.Context(), req.URL, true)
+	if err != nil {
+		return err
+	}
+	return s.scheduler.Wait(req.Context(), strings.ToLower(req.URL.Host), s.hostInterval(crawlDelay), s.maxHostWait())
+}
+
+// redirectOptionsKey identifies the redirect options of a fetch in the document cache
+func redirectOptionsKey(opts types.WebFetchOptions) string {
+	key := make([]string, 0, 3)
+	if opts.MaxRedirects != nil {
+		key = append(key, fmt.Sprint(*opts.MaxRedirects))
+	} else {
+		key = append(key, "-")
+	}
+	for _, option := range []*bool{opts.AllowCrossHostRedirects, opts.RefuseHTTPSDowngrade} {
+		if option != nil {
+			key = append(key, fmt.Sprint(*option))
+		} else {
+			key = append(key, "-")
+		}
+	}
+	return strings.Join(key, "/")
+}
+
+// finalURL returns the URL a response was served from
+func finalURL(resp *http.Response, requested *url.URL) *url.URL {
+	if resp.Request != nil && resp.Request.URL != nil {
+		return resp.Request.URL
+	}
+	return requested
+}
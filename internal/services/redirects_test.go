@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"ez-web-search/internal/config"
+	"ez-web-search/internal/policy"
+	"ez-web-search/pkg/types"
+)
+
+// newTestFetchService creates a fetch service that may reach local test servers without delays
+func newTestFetchService(t *testing.T, rules ...string) *WebFetchService {
+	t.Helper()
+	cfg := config.Load()
+	cfg.WebFetch.SSRF.Enabled = false
+	cfg.WebFetch.Cache.Enabled = false
+	cfg.WebFetch.Polite.Enabled = false
+	cfg.WebFetch.UserAgentRotate = false
+	cfg.WebFetch.Scheduler.MinInterval = 0
+	cfg.WebFetch.Redirects = config.RedirectConfig{MaxRedirects: 2, AllowCrossHost: false, RefuseHTTPSDowngrade: true}
+
+	s := NewWebFetchService(cfg)
+	engine, err := policy.New("allow", rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.policy = engine
+	return s
+}
+
+func TestCheckRedirect(t *testing.T) {
+	s := newTestFetchService(t, "deny host:denied.example", "confirm host:confirm.example")
+	yes, no := true, false
+
+	tests := []struct {
+		name string
+		// chain lists the requested URL and every redirect target; the last one is checked
+		chain   []string
+		opts    types.WebFetchOptions
+		confirm string
+		want    error
+	}{
+		{name: "same host", chain: []string{"https://a.example/", "https://a.example/next"}},
+		{name: "limit reached", chain: []string{"https://a.example/1", "https://a.example/2", "https://a.example/3", "https://a.example/4"}, want: ErrRedirectRefused},
+		{name: "limit raised", chain: []string{"https://a.example/1", "https://a.example/2", "https://a.example/3", "https://a.example/4"}, opts: types.WebFetchOptions{MaxRedirects: intPtr(3)}},
+		{name: "redirects disabled", chain: []string{"https://a.example/", "https://a.example/next"}, opts: types.WebFetchOptions{MaxRedirects: intPtr(0)}, want: ErrRedirectRefused},
+		{name: "cross host", chain: []string{"https://a.example/", "https://b.example/"}, want: ErrRedirectRefused},
+		{name: "cross host allowed", chain: []string{"https://a.example/", "https://b.example/"}, opts: types.WebFetchOptions{AllowCrossHostRedirects: &yes}},
+		{name: "host compared case-insensitively", chain: []string{"https://a.example/", "https://A.EXAMPLE:8443/"}},
+		{name: "back to first host", chain: []string{"https://a.example/", "https://a.example/x", "https://a.example/y"}},
+		{name: "downgrade", chain: []string{"https://a.example/", "http://a.example/"}, want: ErrRedirectRefused},
+		{name: "downgrade allowed", chain: []string{"https://a.example/", "http://a.example/"}, opts: types.WebFetchOptions{RefuseHTTPSDowngrade: &no}},
+		{name: "upgrade", chain: []string{"http://a.example/", "https://a.example/"}},
+		{name: "denied by policy", chain: []string{"https://a.example/", "https://denied.example/"}, opts: types.WebFetchOptions{AllowCrossHostRedirects: &yes}, want: policy.ErrDenied},
+		{name: "confirmation required", chain: []string{"https://a.example/", "https://confirm.example/"}, opts: types.WebFetchOptions{AllowCrossHostRedirects: &yes}, want: policy.ErrConfirmationRequired},
+		{name: "confirmed target", chain: []string{"https://confirm.example/a", "https://confirm.example/b", "https://confirm.example/a"}, confirm: "https://confirm.example/a"},
+		{name: "confirmation bound to its URL", chain: []string{"https://confirm.example/a", "https://confirm.example/b"}, confirm: "https://confirm.example/a", want: policy.ErrConfirmationRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.confirm != "" {
+				approved, _ := url.Parse(tt.confirm)
+				ctx = policy.WithConfirmation(ctx, approved)
+			}
+			tracker := &redirectTracker{settings: s.redirectSettingsFor(tt.opts)}
+			ctx = withRedirectTracker(ctx, tracker)
+
+			var via []*http.Request
+			for _, link := range tt.chain[:len(tt.chain)-1] {
+				via = append(via, newRedirectRequest(t, ctx, link, ""))
+			}
+			req := newRedirectRequest(t, ctx, tt.chain[len(tt.chain)-1], tt.chain[len(tt.chain)-1])
+
+			err := s.checkRedirect(req, via)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("checkRedirect() = %v, want %v", err, tt.want)
+			}
+			if len(tracker.hops) != 1 || tracker.hops[0].URL != tt.chain[len(tt.chain)-2] || tracker.hops[0].StatusCode != http.StatusFound {
+				t.Errorf("recorded hops = %+v, want the hop from %s", tracker.hops, tt.chain[len(tt.chain)-2])
+			}
+		})
+	}
+}
+
+func TestCheckRedirectWithoutTracker(t *testing.T) {
+	s := newTestFetchService(t)
+	ctx := context.Background()
+	via := []*http.Request{newRedirectRequest(t, ctx, "https://a.example/", "")}
+
+	if err := s.checkRedirect(newRedirectRequest(t, ctx, "https://a.example/next", "/next"), via); err != nil {
+		t.Errorf("same-host redirect = %v, want nil", err)
+	}
+	if err := s.checkRedirect(newRedirectRequest(t, ctx, "https://b.example/", "https://b.example/"), via); !errors.Is(err, ErrRedirectRefused) {
+		t.Errorf("cross-host redirect = %v, want the configured refusal", err)
+	}
+}
+
+func TestFetchReportsRedirectChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/middle", http.StatusMovedPermanently)
+		case "/middle":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>Final</title></head><body><p>Arrived.</p></body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	s := newTestFetchService(t)
+	content, err := s.FetchWebPage(context.Background(), types.WebFetchOptions{URL: server.URL + "/start"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content.FinalURL != server.URL+"/final" {
+		t.Errorf("FinalURL = %s, want %s/final", content.FinalURL, server.URL)
+	}
+	want := []types.RedirectHop{
+		{URL: server.URL + "/start", StatusCode: http.StatusMovedPermanently, Location: "/middle"},
+		{URL: server.URL + "/middle", StatusCode: http.StatusFound, Location: "/final"},
+	}
+	if len(content.Redirects) != len(want) {
+		t.Fatalf("Redirects = %+v, want %+v", content.Redirects, want)
+	}
+	for i := range want {
+		if content.Redirects[i] != want[i] {
+			t.Errorf("Redirects[%d] = %+v, want %+v", i, content.Redirects[i], want[i])
+		}
+	}
+
+	_, err = s.FetchWebPage(context.Background(), types.WebFetchOptions{URL: server.URL + "/start", MaxRedirects: intPtr(1)})
+	if !errors.Is(err, ErrRedirectRefused) {
+		t.Errorf("FetchWebPage with MaxRedirects=1 = %v, want a redirect refusal", err)
+	}
+}
+
+// newRedirectRequest builds a request to link; with a location, it is a redirect target that
+// carries the 302 response which led to it
+func newRedirectRequest(t *testing.T, ctx context.Context, link, location string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location != "" {
+		req.Response = &http.Response{StatusCode: http.StatusFound, Header: http.Header{"Location": []string{location}}}
+	}
+	return req
+}
+
+func intPtr(n int) *int {
+	return &n
+}
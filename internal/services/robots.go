@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ez-web-search/internal/policy"
+	"ez-web-search/internal/utils"
+)
+
+// maxRobotsHosts bounds the robots.txt files kept in memory
+const maxRobotsHosts = 1024
+
+// robotsUnavailableTTL is how long an unreachable robots.txt is remembered before it is
+// requested again
+const robotsUnavailableTTL = time.Minute
+
+// robotsEntry is a cached robots.txt file, or the error of an unreachable one
+type robotsEntry struct {
+	robots    *utils.Robots
+	err       error
+	expiresAt time.Time
+}
+
+// robotsLookup is a robots.txt download shared by concurrent fetches from the same origin
+type robotsLookup struct {
+	done   chan struct{}
+	robots *utils.Robots
+	err    error
+}
+
+// robotsCache keeps the parsed robots.txt of each scheme and host and coalesces concurrent
+// downloads of the same one
+type robotsCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	entries  map[string]robotsEntry
+	inflight map[string]*robotsLookup
+}
+
+// newRobotsCache creates a robots.txt cache whose entries expire after ttl
+func newRobotsCache(ttl time.Duration) *robotsCache {
+	return &robotsCache{ttl: ttl, entries: make(map[string]robotsEntry), inflight: make(map[string]*robotsLookup)}
+}
+
+// Get returns the cached robots.txt, or the cached error, of origin
+func (c *robotsCache) Get(origin string) (robotsEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.getLocked(origin)
+}
+
+// getLocked returns a live entry; the caller must hold c.mu
+func (c *robotsCache) getLocked(origin string) (robotsEntry, bool) {
+	entry, ok := c.entries[origin]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return robotsEntry{}, false
+	}
+	return entry, true
+}
+
+// Put stores the robots.txt of origin
+func (c *robotsCache) Put(origin string, robots *utils.Robots) {
+	c.put(origin, robotsEntry{robots: robots}, c.ttl)
+}
+
+// PutUnavailable remembers for a short time that the robots.txt of origin could not be retrieved
+func (c *robotsCache) PutUnavailable(origin string, err error) {
+	c.put(origin, robotsEntry{err: err}, min(c.ttl, robotsUnavailableTTL))
+}
+
+// put stores an entry for ttl, dropping expired entries when the cache is full
+func (c *robotsCache) put(origin string, entry robotsEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxRobotsHosts {
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		// Still full: drop an arbitrary entry
+		for key := range c.entries {
+			if len(c.entries) < maxRobotsHosts {
+				break
+			}
+			delete(c.entries, key)
+		}
+	}
+	entry.expiresAt = now.Add(ttl)
+	c.entries[origin] = entry
+}
+
+// GetOrLoad returns the cached robots.txt of origin, or calls load once for all concurrent
+// callers. The shared load keeps running when the caller that started it gives up, so each
+// caller only ever sees its own context's cancellation.
+func (c *robotsCache) GetOrLoad(ctx context.Context, origin string, load func(ctx context.Context) (*utils.Robots, error)) (*utils.Robots, error) {
+	c.mu.Lock()
+	if entry, ok := c.getLocked(origin); ok {
+		c.mu.Unlock()
+		return entry.robots, entry.err
+	}
+
+	lookup, ok := c.inflight[origin]
+	if !ok {
+		lookup = &robotsLookup{done: make(chan struct{})}
+		c.inflight[origin] = lookup
+		go c.runLoad(context.WithoutCancel(ctx), origin, lookup, load)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-lookup.done:
+		return lookup.robots, lookup.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for robots.txt of %s: %w", origin, ctx.Err())
+	}
+}
+
+// runLoad performs a shared robots.txt download and publishes its result to every waiter,
+// also when load panics
+func (c *robotsCache) runLoad(ctx context.Context, origin string, lookup *robotsLookup, load func(ctx context.Context) (*utils.Robots, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			lookup.robots, lookup.err = nil, fmt.Errorf("robots.txt lookup failed: %v", r)
+		}
+		c.mu.Lock()
+		delete(c.inflight, origin)
+		c.mu.Unlock()
+		close(lookup.done)
+	}()
+
+	lookup.robots, lookup.err = load(ctx)
+}
+
+// checkRobots refuses URLs that robots.txt disallows for the configured product token and
+// returns the host's crawl delay. It does nothing unless polite mode is enabled. holdsSlot
+// tells that the caller already holds a connection slot, as during a redirect.
+func (s *WebFetchService) checkRobots(ctx context.Context, target *url.URL, holdsSlot bool) (time.Duration, error) {
+	polite := s.config.WebFetch.Polite
+	if !polite.Enabled || target.Path == "/robots.txt" {
+		return 0, nil
+	}
+
+	robots, err := s.robotsFor(ctx, target, holdsSlot)
+	if err != nil {
+		return 0, err
+	}
+
+	path := target.EscapedPath()
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+	if !robots.Allowed(polite.ProductToken, path) {
+		return 0, &utils.RobotsDisallowedError{URL: target.String(), Agent: polite.ProductToken}
+	}
+	return robots.CrawlDelay(polite.ProductToken), nil
+}
+
+// robotsFor returns the robots.txt rules of the target's origin, downloading them if needed.
+// Concurrent lookups of an origin share one download, except during a redirect: the caller
+// then holds a connection slot the shared download may be waiting for, so it downloads
+// robots.txt itself.
+func (s *WebFetchService) robotsFor(ctx context.Context, target *url.URL, holdsSlot bool) (*utils.Robots, error) {
+	origin := strings.ToLower(target.Scheme + "://" + target.Host)
+	if entry, ok := s.robots.Get(origin); ok {
+		return entry.robots, entry.err
+	}
+
+	// robots.txt is requested on behalf of target and shares the user's confirmation of it
+	robotsURL := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/robots.txt"}
+	if err := s.policy.Check(robotsURL, policy.Confirmed(ctx, target)); err != nil {
+		return nil, err
+	}
+
+	load := func(ctx context.Context) (*utils.Robots, error) {
+		return s.loadRobots(ctx, origin, robotsURL, holdsSlot)
+	}
+	if holdsSlot {
+		return load(ctx)
+	}
+	return s.robots.GetOrLoad(ctx, origin, load)
+}
+
+// loadRobots downloads robots.txt like any other request to its host and caches the result.
+// An unreachable robots.txt is remembered for robotsUnavailableTTL.
+func (s *WebFetchService) loadRobots(ctx context.Context, origin string, robotsURL *url.URL, holdsSlot bool) (*utils.Robots, error) {
+	host := strings.ToLower(robotsURL.Host)
+	if holdsSlot {
+		if err := s.scheduler.Wait(ctx, host, s.hostInterval(0), s.maxHostWait()); err != nil {
+			return nil, err
+		}
+	} else {
+		release, err := s.scheduler.Acquire(ctx, host, s.hostInterval(0), s.maxHostWait())
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	robots, unavailable, err := s.downloadRobots(ctx, robotsURL)
+	switch {
+	case err == nil:
+		s.robots.Put(origin, robots)
+	case unavailable && ctx.Err() == nil:
+		s.robots.PutUnavailable(origin, err)
+	}
+	return robots, err
+}
+
+// downloadRobots requests and parses robots.txt. A missing robots.txt (4xx) allows everything;
+// an unreachable one (5xx, 429 or a network error) refuses the fetch, since the site's wishes
+// cannot be known, and is reported as unavailable.
+func (s *WebFetchService) downloadRobots(ctx context.Context, robotsURL *url.URL) (*utils.Robots, bool, error) {
+	// Redirects of robots.txt follow the configured policy and are not part of the page's chain
+	ctxWithTimeout, cancel := context.WithTimeout(withRedirectTracker(ctx, nil), s.config.WebFetch.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctxWithTimeout, "GET", robotsURL.String(), nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create robots.txt request: %w", err)
+	}
+	req.Header.Set("User-Agent", s.config.WebFetch.Polite.UserAgent)
+	req.Header.Set("Accept", "text/plain, */*;q=0.5")
+	req.Header.Set("Accept-Encoding", utils.AcceptEncoding)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		// A redirect needing the user's confirmation depends on the caller, not on the site
+		unavailable := !errors.Is(err, policy.ErrConfirmationRequired)
+		return nil, unavailable, fmt.Errorf("failed to fetch robots.txt for %s: %w", robotsURL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// Rules beyond the size limit are ignored, as RFC 9309 permits
+		body, err := utils.ReadResponseBody(resp, utils.MaxRobotsSize, true)
+		if err != nil && len(body) == 0 {
+			return nil, true, fmt.Errorf("failed to read robots.txt for %s: %w", robotsURL.Host, err)
+		}
+		return utils.ParseRobots(body), false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("robots.txt for %s is unavailable (status %d), refusing to fetch without it", robotsURL.Host, resp.StatusCode)
+	default:
+		return utils.NewRobotsAllowAll(), false, nil
+	}
+}
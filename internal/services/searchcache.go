@@ -0,0 +1,208 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ez-web-search/pkg/types"
+)
+
+// searchCacheEntry is a cached search response stored in the LRU list
+type searchCacheEntry struct {
+	key       string
+	response  *types.SearchResponse
+	expiresAt time.Time
+}
+
+// inflightSearch is an upstream search shared by concurrent identical requests
+type inflightSearch struct {
+	done     chan struct{}
+	response *types.SearchResponse
+	err      error
+}
+
+// SearchCache is a size-bounded LRU cache of search responses with a TTL.
+// Concurrent misses for the same key are coalesced into a single upstream call.
+type SearchCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	maxSize  int
+	entries  map[string]*list.Element
+	lru      *list.List
+	inflight map[string]*inflightSearch
+	now      func() time.Time
+}
+
+// NewSearchCache creates a search cache; a non-positive ttl or size disables caching
+// but identical in-flight requests are still coalesced
+func NewSearchCache(ttl time.Duration, maxSize int) *SearchCache {
+	return &SearchCache{
+		ttl:      ttl,
+		maxSize:  maxSize,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[string]*inflightSearch),
+		now:      time.Now,
+	}
+}
+
+// sharedSearchTimeout bounds an upstream search shared by coalesced callers, which runs
+// detached from the context of any single caller
+const sharedSearchTimeout = 2 * time.Minute
+
+// GetOrSearch returns the cached response for key, or calls search exactly once
+// for all concurrent callers with the same key and caches a successful result.
+// The shared call keeps running when the caller that started it gives up, so each
+// caller only ever sees its own context's cancellation.
+func (c *SearchCache) GetOrSearch(ctx context.Context, key string, search func(ctx context.Context) (*types.SearchResponse, error)) (*types.SearchResponse, error) {
+	c.mu.Lock()
+	if resp, ok := c.getLocked(key); ok {
+		c.mu.Unlock()
+		resp.Cached = true
+		return resp, nil
+	}
+
+	call, ok := c.inflight[key]
+	if !ok {
+		call = &inflightSearch{done: make(chan struct{})}
+		c.inflight[key] = call
+		go c.runSearch(context.WithoutCancel(ctx), key, call, search)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		return copySearchResponse(call.response), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runSearch performs a shared upstream search and publishes its result to every waiter,
+// also when search panics
+func (c *SearchCache) runSearch(ctx context.Context, key string, call *inflightSearch, search func(ctx context.Context) (*types.SearchResponse, error)) {
+	ctx, cancel := context.WithTimeout(ctx, sharedSearchTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			call.response, call.err = nil, fmt.Errorf("search failed: %v", r)
+		}
+		c.mu.Lock()
+		delete(c.inflight, key)
+		if call.err == nil {
+			c.putLocked(key, call.response)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.response, call.err = search(ctx)
+}
+
+// getLocked returns a copy of a live cache entry; the caller must hold c.mu
+func (c *SearchCache) getLocked(key string) (*types.SearchResponse, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*searchCacheEntry)
+	if c.now().After(entry.expiresAt) {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+	return copySearchResponse(entry.response), true
+}
+
+// putLocked stores a response and evicts the least recently used entries; the caller must hold c.mu
+func (c *SearchCache) putLocked(key string, resp *types.SearchResponse) {
+	if c.ttl <= 0 || c.maxSize <= 0 {
+		return
+	}
+
+	entry := &searchCacheEntry{
+		key:       key,
+		response:  copySearchResponse(resp),
+		expiresAt: c.now().Add(c.ttl),
+	}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*searchCacheEntry).key)
+	}
+}
+
+// copySearchResponse copies a response so callers cannot mutate cached data
+func copySearchResponse(resp *types.SearchResponse) *types.SearchResponse {
+	if resp == nil {
+		return nil
+	}
+
+	copied := *resp
+	copied.SearchIntent = append([]types.SearchIntent(nil), resp.SearchIntent...)
+	copied.SearchResult = make([]types.SearchResult, len(resp.SearchResult))
+	for i, result := range resp.SearchResult {
+		result.Engines = append([]string(nil), result.Engines...)
+		copied.SearchResult[i] = result
+	}
+	if resp.Errors != nil {
+		copied.Errors = make(map[string]string, len(resp.Errors))
+		for name, msg := range resp.Errors {
+			copied.Errors[name] = msg
+		}
+	}
+	return &copied
+}
+
+// searchCacheKey builds a cache key from every option that changes the result set.
+// Request and user IDs are pass-through metadata and deliberately excluded.
+func searchCacheKey(opts types.WebSearchOptions, defaultEngine string) string {
+	engine := opts.SearchEngine
+	if engine == "" {
+		engine = defaultEngine
+	}
+
+	return strings.Join([]string{
+		normalizeQuery(opts.Query),
+		engine,
+		sortedKey(opts.SearchEngines),
+		fmt.Sprintf("%t", opts.SearchIntent),
+		fmt.Sprintf("%d", opts.Count),
+		strings.ToLower(opts.DomainFilter),
+		opts.RecencyFilter,
+		opts.ContentSize,
+		sortedKey(normalizeDomains(opts.IncludeDomains)),
+		sortedKey(normalizeDomains(opts.ExcludeDomains)),
+	}, "\x00")
+}
+
+// normalizeQuery lowercases a query and collapses whitespace
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// sortedKey joins a sorted copy of values
+func sortedKey(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
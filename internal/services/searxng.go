@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"ez-web-search/internal/config"
+	"ez-web-search/internal/utils"
+	"ez-web-search/pkg/types"
+)
+
+// SearXNGProviderName is the name under which the SearXNG provider is registered
+const SearXNGProviderName = "searxng"
+
+// SearXNGProvider searches through the JSON API of a self-hosted SearXNG instance
+type SearXNGProvider struct {
+	config     *config.Config
+	httpClient *http.Client
+}
+
+// searxngResponse represents the subset of the SearXNG JSON response we consume
+type searxngResponse struct {
+	Query   string          `json:"query"`
+	Results []searxngResult `json:"results"`
+}
+
+// searxngResult represents a single SearXNG search result
+type searxngResult struct {
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	Content       string   `json:"content"`
+	Engine        string   `json:"engine"`
+	Engines       []string `json:"engines"`
+	PublishedDate string   `json:"publishedDate"`
+	Thumbnail     string   `json:"thumbnail"`
+}
+
+// NewSearXNGProvider creates a new SearXNG provider
+func NewSearXNGProvider(cfg *config.Config) *SearXNGProvider {
+	return &SearXNGProvider{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout: cfg.SearXNG.Timeout,
+		},
+	}
+}
+
+// Name returns the provider name
+func (p *SearXNGProvider) Name() string {
+	return SearXNGProviderName
+}
+
+// Search performs a web search using the SearXNG JSON API
+func (p *SearXNGProvider) Search(ctx context.Context, opts types.WebSearchOptions) (*types.SearchResponse, error) {
+	endpoint, err := url.Parse(strings.TrimRight(p.config.SearXNG.BaseURL, "/") + "/search")
+	if err != nil {
+		return nil, fmt.Errorf("invalid SearXNG base URL: %w", err)
+	}
+
+	// SearXNG has no domain parameter, but its engines understand the site: operator
+	searchQuery := opts.Query
+	if opts.DomainFilter != "" {
+		searchQuery = fmt.Sprintf("site:%s %s", opts.DomainFilter, searchQuery)
+	}
+
+	query := endpoint.Query()
+	query.Set("q", searchQuery)
+	query.Set("format", "json")
+	if p.config.SearXNG.Categories != "" {
+		query.Set("categories", p.config.SearXNG.Categories)
+	}
+	if p.config.SearXNG.Language != "" {
+		query.Set("language", p.config.SearXNG.Language)
+	}
+	query.Set("safesearch", strconv.Itoa(p.config.SearXNG.SafeSearch))
+	if opts.RecencyFilter != "" {
+		query.Set("time_range", opts.RecencyFilter)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", utils.AcceptEncoding)
+	req.Header.Set("User-Agent", fmt.Sprintf("%s/%s", p.config.Server.Name, p.config.Server.Version))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read and decode the response body within the size limit
+	body, err := utils.ReadResponseBody(resp, p.config.Search.MaxResponseSize, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ProviderError{
+			Provider:   SearXNGProviderName,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("SearXNG request failed with status %d: %s", resp.StatusCode, string(body)),
+		}
+	}
+
+	var searxResp searxngResponse
+	if err := json.Unmarshal(body, &searxResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	searchResp := &types.SearchResponse{
+		Provider:  SearXNGProviderName,
+		RequestID: opts.RequestID,
+	}
+	for _, result := range searxResp.Results {
+		if result.URL == "" {
+			continue
+		}
+		searchResp.SearchResult = append(searchResp.SearchResult, types.SearchResult{
+			Title:       strings.TrimSpace(result.Title),
+			Content:     strings.TrimSpace(result.Content),
+			Link:        result.URL,
+			Media:       hostOf(result.URL),
+			Icon:        result.Thumbnail,
+			Refer:       strings.Join(result.Engines, ", "),
+			PublishDate: result.PublishedDate,
+		})
+		if opts.Count > 0 && len(searchResp.SearchResult) >= opts.Count {
+			break
+		}
+	}
+
+	return searchResp, nil
+}
+
+// hostOf returns the host part of rawURL, or an empty string if it cannot be parsed
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
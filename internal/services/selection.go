@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+
+	"ez-web-search/pkg/types"
+)
+
+// Values extracted from the nodes matched by a selector or XPath query
+const (
+	ExtractText      = "text"
+	ExtractHTML      = "html"
+	ExtractAttribute = "attribute"
+)
+
+// maxSelectorMatches caps the number of matches returned for one query
+const maxSelectorMatches = 100
+
+// urlAttributes are resolved to absolute URLs when extracted
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "data": true, "poster": true, "cite": true,
+}
+
+// extractMatches replaces the main content with the values of the nodes matched by the CSS
+// selector or XPath query in the fetch options
+func extractMatches(doc *goquery.Document, fetched *FetchedDocument, content *types.WebPageContent) error {
+	opts := fetched.Options
+
+	var nodes []*html.Node
+	var values []string
+	query := opts.Selector
+	switch {
+	case opts.Selector != "":
+		selector, err := cascadia.Compile(opts.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid CSS selector %q: %w", opts.Selector, err)
+		}
+		nodes = doc.FindMatcher(selector).Nodes
+	case opts.XPath != "":
+		query = opts.XPath
+		expr, err := xpath.Compile(opts.XPath)
+		if err != nil {
+			return fmt.Errorf("invalid XPath expression %q: %w", opts.XPath, err)
+		}
+		// Expressions such as count(//a) or string(//title) evaluate to a single value
+		switch result := expr.Evaluate(htmlquery.CreateXPathNavigator(doc.Nodes[0])).(type) {
+		case *xpath.NodeIterator:
+			nodes = htmlquery.QuerySelectorAll(doc.Nodes[0], expr)
+		case float64:
+			values = append(values, strconv.FormatFloat(result, 'f', -1, 64))
+		default:
+			values = append(values, fmt.Sprint(result))
+		}
+	}
+
+	for _, node := range nodes {
+		value, ok := matchValue(node, opts, fetched.URL)
+		if ok && strings.TrimSpace(value) != "" {
+			values = append(values, value)
+		}
+	}
+
+	content.MatchCount = len(values)
+	if !opts.AllMatches && len(values) > 1 {
+		values = values[:1]
+	}
+	if len(values) > maxSelectorMatches {
+		values = values[:maxSelectorMatches]
+	}
+	content.Matches = values
+
+	switch opts.Extract {
+	case ExtractHTML:
+		content.Format = FormatHTML
+	case ExtractAttribute:
+		content.Format = FormatText
+	}
+
+	if len(values) == 1 {
+		content.Content = values[0]
+	} else {
+		var matches strings.Builder
+		for i, value := range values {
+			matches.WriteString(fmt.Sprintf("--- Match %d ---\n%s\n\n", i+1, value))
+		}
+		content.Content = strings.TrimSpace(matches.String())
+	}
+	if content.MatchCount == 0 {
+		content.Content = fmt.Sprintf("No nodes matched %s", query)
+	}
+	return nil
+}
+
+// matchValue returns the value of a matched node: its text in the requested output format,
+// its inner HTML or one of its attributes
+func matchValue(node *html.Node, opts types.WebFetchOptions, baseURL *url.URL) (string, bool) {
+	selection := goquery.NewDocumentFromNode(node).Selection
+
+	switch opts.Extract {
+	case ExtractHTML:
+		if node.Type != html.ElementNode {
+			return html.EscapeString(node.Data), true
+		}
+		inner, err := selection.Html()
+		return strings.TrimSpace(inner), err == nil
+	case ExtractAttribute:
+		value, ok := selection.Attr(opts.Attribute)
+		if !ok {
+			return "", false
+		}
+		value = strings.TrimSpace(value)
+		if urlAttributes[strings.ToLower(opts.Attribute)] {
+			if resolved, err := baseURL.Parse(value); err == nil {
+				value = resolved.String()
+			}
+		}
+		return value, true
+	}
+
+	switch opts.Format {
+	case FormatMarkdown:
+		return htmlToMarkdown([]*html.Node{node}, baseURL, opts.LinkStyle), true
+	case FormatHTML:
+		outer, err := goquery.OuterHtml(selection)
+		return strings.TrimSpace(outer), err == nil
+	}
+
+	// Code and preformatted text keep their line breaks
+	if preformatted(node) {
+		return strings.Trim(selection.Text(), "\n"), true
+	}
+	return strings.Join(strings.Fields(selection.Text()), " "), true
+}
+
+// preformatted reports whether whitespace is significant in a node
+func preformatted(node *html.Node) bool {
+	for n := node; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode && (n.Data == "pre" || n.Data == "textarea") {
+			return true
+		}
+	}
+	return node.Type == html.ElementNode && goquery.NewDocumentFromNode(node).Find("pre").Length() > 0
+}
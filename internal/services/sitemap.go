@@ -0,0 +1,417 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"ez-web-search/internal/utils"
+	"ez-web-search/pkg/types"
+)
+
+// URL limits for ez_sitemap
+const (
+	DefaultSitemapLimit = 100
+	MaxSitemapLimit     = 1000
+)
+
+// Bounds on the sitemaps read for one request, so that huge sitemap trees cannot exhaust it
+const (
+	maxSitemapFiles = 50
+	maxSitemapDepth = 3
+)
+
+// sitemapDateLayouts are the W3C Datetime profiles allowed for <lastmod>
+var sitemapDateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// SitemapService discovers and reads sitemaps through the web fetch HTTP stack
+type SitemapService struct {
+	fetcher *WebFetchService
+}
+
+// NewSitemapService creates a sitemap service that downloads sitemaps with fetcher
+func NewSitemapService(fetcher *WebFetchService) *SitemapService {
+	return &SitemapService{fetcher: fetcher}
+}
+
+// sitemapRef is a sitemap waiting to be read, with its nesting depth below the root sitemaps
+type sitemapRef struct {
+	url   string
+	depth int
+}
+
+// ReadSitemaps collects the URLs listed in a site's sitemaps. A sitemap URL is read directly;
+// for any other URL the sitemaps are discovered from robots.txt, falling back to /sitemap.xml.
+// Sitemap indexes are followed recursively.
+func (s *SitemapService) ReadSitemaps(ctx context.Context, opts types.SitemapOptions) (*types.Sitemap, error) {
+	siteURL, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if siteURL.Scheme != "http" && siteURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme: %s", siteURL.Scheme)
+	}
+
+	var roots []string
+	if isSitemapURL(siteURL) {
+		roots = []string{siteURL.String()}
+	} else {
+		roots = s.discoverSitemaps(ctx, siteURL)
+	}
+
+	result := &types.Sitemap{Site: opts.URL}
+	visited := make(map[string]bool)
+	var queue []sitemapRef
+	for _, root := range roots {
+		if !visited[root] {
+			visited[root] = true
+			queue = append(queue, sitemapRef{url: root})
+		}
+	}
+
+	attempted := 0
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if attempted >= maxSitemapFiles {
+			result.Errors = append(result.Errors, fmt.Sprintf("stopped after %d sitemaps; %d more were not read", maxSitemapFiles, len(queue)))
+			break
+		}
+
+		ref := queue[0]
+		queue = queue[1:]
+		attempted++
+
+		urls, children, err := s.readSitemap(ctx, ref.url)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", ref.url, err))
+			continue
+		}
+		result.Sitemaps = append(result.Sitemaps, ref.url)
+
+		for _, child := range children {
+			if visited[child.Loc] {
+				continue
+			}
+			visited[child.Loc] = true
+			if ref.depth+1 > maxSitemapDepth {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: sitemap indexes nested deeper than %d levels", child.Loc, maxSitemapDepth))
+				continue
+			}
+			// A sitemap last modified before the cutoff cannot list newer URLs
+			if !opts.Since.IsZero() && child.LastMod != "" && sitemapModifiedBefore(child.LastMod, opts.Since) {
+				continue
+			}
+			queue = append(queue, sitemapRef{url: child.Loc, depth: ref.depth + 1})
+		}
+
+		for _, entry := range urls {
+			result.TotalURLs++
+			if !matchSitemapURL(entry, opts.PathPrefix, opts.Since) {
+				continue
+			}
+			result.MatchedURLs++
+			if opts.Limit <= 0 || len(result.URLs) < opts.Limit {
+				result.URLs = append(result.URLs, entry)
+			}
+		}
+	}
+
+	if len(result.Sitemaps) == 0 {
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("no sitemap could be read: %s", result.Errors[0])
+		}
+		return nil, fmt.Errorf("no sitemap found for %s", opts.URL)
+	}
+	return result, nil
+}
+
+// discoverSitemaps returns the sitemaps declared in the site's robots.txt, or /sitemap.xml
+func (s *SitemapService) discoverSitemaps(ctx context.Context, siteURL *url.URL) []string {
+	robotsURL := &url.URL{Scheme: siteURL.Scheme, Host: siteURL.Host, Path: "/robots.txt"}
+	fetched, err := s.fetcher.Fetch(ctx, types.WebFetchOptions{URL: robotsURL.String()})
+	if err == nil && fetched.StatusCode < 400 {
+		if sitemaps := robotsSitemaps(fetched.Body, robotsURL); len(sitemaps) > 0 {
+			return sitemaps
+		}
+	}
+	return []string{(&url.URL{Scheme: siteURL.Scheme, Host: siteURL.Host, Path: "/sitemap.xml"}).String()}
+}
+
+// robotsSitemaps returns the absolute URLs of the Sitemap: lines of a robots.txt file
+func robotsSitemaps(body []byte, robotsURL *url.URL) []string {
+	var sitemaps []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(field), "sitemap") {
+			continue
+		}
+		if sitemap, err := robotsURL.Parse(strings.TrimSpace(value)); err == nil && sitemap.Host != "" {
+			sitemaps = append(sitemaps, sitemap.String())
+		}
+	}
+	return sitemaps
+}
+
+// isSitemapURL reports whether a URL names a sitemap file rather than a site
+func isSitemapURL(u *url.URL) bool {
+	name := strings.ToLower(u.Path)
+	name = strings.TrimSuffix(name, ".gz")
+	return strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".txt") && !strings.HasSuffix(name, "/robots.txt")
+}
+
+// readSitemap fetches a sitemap and returns its URLs and, for a sitemap index, its child sitemaps
+func (s *SitemapService) readSitemap(ctx context.Context, sitemapURL string) ([]types.SitemapURL, []types.SitemapURL, error) {
+	fetched, err := s.fetcher.Fetch(ctx, types.WebFetchOptions{URL: sitemapURL})
+	if err != nil {
+		return nil, nil, err
+	}
+	if fetched.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("sitemap request failed with status %d", fetched.StatusCode)
+	}
+
+	// The fetcher undoes Content-Encoding and sniffs a bare .gz body; a .gz file that was
+	// additionally served with Content-Encoding: gzip is still compressed at this point
+	body := fetched.Body
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		if body, err = s.gunzip(body); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	body, err = utils.ToUTF8(body, utils.DetectCharset(body, fetched.ContentType))
+	if err != nil {
+		return nil, nil, err
+	}
+	body = bytes.TrimSpace(body)
+	if !bytes.HasPrefix(body, []byte("<")) {
+		return parseTextSitemap(body, fetched.URL), nil, nil
+	}
+	return parseXMLSitemap(body, fetched.URL, fetched.Truncated)
+}
+
+// gunzip decompresses a gzip file within the body size limit
+func (s *SitemapService) gunzip(body []byte) ([]byte, error) {
+	reader, err := utils.NewDecodingReader(bytes.NewReader(body), "gzip")
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// A non-positive limit disables the size check
+	var source io.Reader = reader
+	limit := s.fetcher.config.WebFetch.MaxBodySize
+	if limit > 0 {
+		source = io.LimitReader(reader, limit+1)
+	}
+	decoded, err := io.ReadAll(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+	}
+	if limit > 0 && int64(len(decoded)) > limit {
+		return nil, &utils.ContentTooLargeError{Limit: limit, Decoded: true}
+	}
+	return decoded, nil
+}
+
+// xmlSitemapEntry is a <url> element of a urlset or a <sitemap> element of a sitemap index
+type xmlSitemapEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+// parseXMLSitemap parses a urlset or sitemap index. RSS and Atom feeds, which the sitemap
+// protocol also accepts, are read through the feed parser. A truncated body yields the
+// entries read before the cut.
+func parseXMLSitemap(body []byte, baseURL *url.URL, truncated bool) ([]types.SitemapURL, []types.SitemapURL, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	// The body has already been transcoded to UTF-8 whatever the declaration says
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var root string
+	var urls, children []types.SitemapURL
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if truncated && root != "" {
+				break
+			}
+			return nil, nil, fmt.Errorf("failed to parse sitemap: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = strings.ToLower(start.Name.Local)
+			switch root {
+			case "urlset", "sitemapindex":
+				continue
+			case "rss", "rdf", "feed":
+				return parseFeedSitemap(body, baseURL)
+			default:
+				return nil, nil, fmt.Errorf("not a sitemap: unexpected root element <%s>", start.Name.Local)
+			}
+		}
+
+		name := strings.ToLower(start.Name.Local)
+		if name != "url" && name != "sitemap" {
+			continue
+		}
+		var entry xmlSitemapEntry
+		if err := decoder.DecodeElement(&entry, &start); err != nil {
+			if truncated {
+				break
+			}
+			return nil, nil, fmt.Errorf("failed to parse sitemap: %w", err)
+		}
+		loc := resolveFeedURL(baseURL, entry.Loc)
+		if loc == "" {
+			continue
+		}
+		normalized := types.SitemapURL{
+			Loc:        loc,
+			LastMod:    formatSitemapDate(entry.LastMod),
+			ChangeFreq: strings.TrimSpace(entry.ChangeFreq),
+			Priority:   strings.TrimSpace(entry.Priority),
+		}
+		if name == "sitemap" {
+			children = append(children, normalized)
+		} else {
+			urls = append(urls, normalized)
+		}
+	}
+	return urls, children, nil
+}
+
+// parseFeedSitemap reads the entry links of an RSS or Atom feed used as a sitemap
+func parseFeedSitemap(body []byte, baseURL *url.URL) ([]types.SitemapURL, []types.SitemapURL, error) {
+	feed, err := parseXMLFeed(body, baseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	var urls []types.SitemapURL
+	for _, entry := range feed.Entries {
+		if entry.Link != "" {
+			urls = append(urls, types.SitemapURL{Loc: entry.Link, LastMod: entry.Published})
+		}
+	}
+	return urls, nil, nil
+}
+
+// parseTextSitemap parses a plain text sitemap holding one URL per line
+func parseTextSitemap(body []byte, baseURL *url.URL) []types.SitemapURL {
+	var urls []types.SitemapURL
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "http://") && !strings.HasPrefix(line, "https://") {
+			continue
+		}
+		urls = append(urls, types.SitemapURL{Loc: resolveFeedURL(baseURL, line)})
+	}
+	return urls
+}
+
+// matchSitemapURL applies the path prefix and date filters. URLs without a lastmod are
+// dropped when a date filter is set.
+func matchSitemapURL(entry types.SitemapURL, pathPrefix string, since time.Time) bool {
+	if pathPrefix != "" {
+		if strings.HasPrefix(pathPrefix, "http://") || strings.HasPrefix(pathPrefix, "https://") {
+			if !strings.HasPrefix(entry.Loc, pathPrefix) {
+				return false
+			}
+		} else {
+			loc, err := url.Parse(entry.Loc)
+			if err != nil || !strings.HasPrefix(loc.Path, "/"+strings.TrimPrefix(pathPrefix, "/")) {
+				return false
+			}
+		}
+	}
+	if !since.IsZero() {
+		if entry.LastMod == "" || sitemapModifiedBefore(entry.LastMod, since) {
+			return false
+		}
+	}
+	return true
+}
+
+// sitemapModifiedBefore reports whether a normalized lastmod lies before since
+func sitemapModifiedBefore(lastMod string, since time.Time) bool {
+	modified, err := time.Parse(time.RFC3339, lastMod)
+	return err == nil && modified.Before(since)
+}
+
+// formatSitemapDate normalizes a lastmod value to RFC 3339 in UTC, or returns "" if it cannot be parsed
+func formatSitemapDate(value string) string {
+	value = strings.TrimSpace(value)
+	for _, layout := range sitemapDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC().Format(time.RFC3339)
+		}
+	}
+	return formatFeedDate(value)
+}
+
+// FormatSitemap formats sitemap URLs for display
+func (s *SitemapService) FormatSitemap(sitemap *types.Sitemap) string {
+	var resultText string
+	resultText += fmt.Sprintf("Site: %s\n", sitemap.Site)
+	resultText += fmt.Sprintf("Sitemaps Read: %d\n", len(sitemap.Sitemaps))
+	for _, sitemapURL := range sitemap.Sitemaps {
+		resultText += fmt.Sprintf("  - %s\n", sitemapURL)
+	}
+	if len(sitemap.Errors) > 0 {
+		resultText += "Errors:\n"
+		for _, sitemapErr := range sitemap.Errors {
+			resultText += fmt.Sprintf("  - %s\n", sitemapErr)
+		}
+	}
+	resultText += fmt.Sprintf("URLs: %d shown, %d matching, %d total\n\n", len(sitemap.URLs), sitemap.MatchedURLs, sitemap.TotalURLs)
+
+	for i, entry := range sitemap.URLs {
+		resultText += fmt.Sprintf("%d. %s", i+1, entry.Loc)
+		var details []string
+		if entry.LastMod != "" {
+			details = append(details, "lastmod: "+entry.LastMod)
+		}
+		if entry.Priority != "" {
+			details = append(details, "priority: "+entry.Priority)
+		}
+		if entry.ChangeFreq != "" {
+			details = append(details, "changefreq: "+entry.ChangeFreq)
+		}
+		if len(details) > 0 {
+			resultText += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+		}
+		resultText += "\n"
+	}
+
+	return resultText
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"ez-web-search/pkg/types"
+)
+
+// itemSyntax describes the attributes of a scoped item syntax (Microdata or RDFa Lite)
+type itemSyntax struct {
+	// scope marks an item; prop names a property of the enclosing item
+	scope, prop string
+	// types and id hold the item type and global identifier
+	types, id string
+}
+
+// Microdata and RDFa Lite share the same item/property model with different attributes
+var (
+	microdataSyntax = itemSyntax{scope: "itemscope", prop: "itemprop", types: "itemtype", id: "itemid"}
+	rdfaSyntax      = itemSyntax{scope: "typeof", prop: "property", types: "typeof", id: "resource"}
+)
+
+// publishedMetaSelectors and modifiedMetaSelectors find page dates outside OpenGraph and JSON-LD
+var (
+	publishedMetaSelectors = []string{
+		"meta[name='date']", "meta[name='pubdate']", "meta[name='publish-date']",
+		"meta[name='dc.date']", "meta[name='DC.date']", "meta[itemprop='datePublished']",
+	}
+	modifiedMetaSelectors = []string{
+		"meta[name='last-modified']", "meta[name='dc.modified']", "meta[name='DC.modified']",
+		"meta[itemprop='dateModified']",
+	}
+)
+
+// extractStructuredData collects the JSON-LD, Microdata, RDFa, OpenGraph and Twitter Card data
+// of an HTML document, and derives the canonical URL, site name and publication dates from it
+func extractStructuredData(doc *goquery.Document, baseURL *url.URL) *types.StructuredData {
+	data := &types.StructuredData{
+		OpenGraph:   make(map[string]string),
+		TwitterCard: make(map[string]string),
+	}
+
+	// OpenGraph uses property=, but many sites put og: and twitter: tags in name= as well
+	doc.Find("meta[property], meta[name]").Each(func(i int, meta *goquery.Selection) {
+		key := strings.ToLower(strings.TrimSpace(meta.AttrOr("property", meta.AttrOr("name", ""))))
+		value := strings.TrimSpace(meta.AttrOr("content", ""))
+		if value == "" {
+			return
+		}
+		var target map[string]string
+		switch {
+		case strings.HasPrefix(key, "og:"), strings.HasPrefix(key, "article:"):
+			target = data.OpenGraph
+		case strings.HasPrefix(key, "twitter:"):
+			target = data.TwitterCard
+		default:
+			return
+		}
+		if _, exists := target[key]; !exists {
+			target[key] = value
+		}
+	})
+
+	doc.Find("script[type='application/ld+json']").Each(func(i int, script *goquery.Selection) {
+		value, err := parseJSONLD(script.Text())
+		if err != nil {
+			data.Errors = append(data.Errors, fmt.Sprintf("JSON-LD block %d: %v", i+1, err))
+			return
+		}
+		if value != nil {
+			data.JSONLD = append(data.JSONLD, value)
+		}
+	})
+
+	data.Microdata = extractItems(doc, microdataSyntax, baseURL)
+	data.RDFa = extractItems(doc, rdfaSyntax, baseURL)
+
+	if href := strings.TrimSpace(doc.Find("link[rel='canonical']").AttrOr("href", "")); href != "" {
+		if canonical, err := baseURL.Parse(href); err == nil {
+			data.CanonicalURL = canonical.String()
+		}
+	}
+	if data.CanonicalURL == "" {
+		data.CanonicalURL = data.OpenGraph["og:url"]
+	}
+
+	data.SiteName = firstNonEmpty(
+		data.OpenGraph["og:site_name"],
+		jsonLDSiteName(data.JSONLD),
+		doc.Find("meta[name='application-name']").AttrOr("content", ""),
+	)
+
+	data.PublishedTime = normalizeStructuredDate(firstNonEmpty(
+		data.OpenGraph["article:published_time"],
+		jsonLDString(data.JSONLD, "datePublished"),
+		itemString(data.Microdata, "datePublished"),
+		itemString(data.RDFa, "datePublished"),
+		firstMetaContent(doc, publishedMetaSelectors),
+	))
+	data.ModifiedTime = normalizeStructuredDate(firstNonEmpty(
+		data.OpenGraph["article:modified_time"],
+		data.OpenGraph["og:updated_time"],
+		jsonLDString(data.JSONLD, "dateModified"),
+		itemString(data.Microdata, "dateModified"),
+		itemString(data.RDFa, "dateModified"),
+		firstMetaContent(doc, modifiedMetaSelectors),
+	))
+
+	if len(data.OpenGraph) == 0 {
+		data.OpenGraph = nil
+	}
+	if len(data.TwitterCard) == 0 {
+		data.TwitterCard = nil
+	}
+	return data
+}
+
+// parseJSONLD decodes a JSON-LD script block, tolerating the comment and CDATA wrappers
+// that some pages put around it
+func parseJSONLD(text string) (any, error) {
+	text = strings.TrimSpace(text)
+	for _, wrapper := range []string{"<!--", "-->", "//<![CDATA[", "//]]>", "<![CDATA[", "]]>"} {
+		text = strings.ReplaceAll(text, wrapper, "")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return value, nil
+}
+
+// jsonLDString returns the first string value of key found anywhere in the JSON-LD blocks
+func jsonLDString(blocks []any, key string) string {
+	for _, block := range blocks {
+		for _, value := range jsonDescendants(block) {
+			object, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			if text, ok := object[key].(string); ok && strings.TrimSpace(text) != "" {
+				return strings.TrimSpace(text)
+			}
+		}
+	}
+	return ""
+}
+
+// jsonLDSiteName returns the name of the WebSite object, or of the publisher
+func jsonLDSiteName(blocks []any) string {
+	for _, block := range blocks {
+		for _, value := range jsonDescendants(block) {
+			object, ok := value.(map[string]any)
+			if ok && jsonLDHasType(object, "WebSite") {
+				if name, ok := object["name"].(string); ok && strings.TrimSpace(name) != "" {
+					return strings.TrimSpace(name)
+				}
+			}
+		}
+	}
+	for _, block := range blocks {
+		for _, value := range jsonDescendants(block) {
+			object, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			if publisher, ok := object["publisher"].(map[string]any); ok {
+				if name, ok := publisher["name"].(string); ok && strings.TrimSpace(name) != "" {
+					return strings.TrimSpace(name)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// jsonLDHasType reports whether a JSON-LD object has the given @type
+func jsonLDHasType(object map[string]any, name string) bool {
+	switch typed := object["@type"].(type) {
+	case string:
+		return typed == name
+	case []any:
+		for _, value := range typed {
+			if value == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractItems returns the top-level items of a scoped item syntax, with nested items as
+// property values. Properties belong to the nearest enclosing item.
+func extractItems(doc *goquery.Document, syntax itemSyntax, baseURL *url.URL) []types.StructuredItem {
+	var items []types.StructuredItem
+	doc.Find("[" + syntax.scope + "]").Each(func(i int, element *goquery.Selection) {
+		// Items that are themselves property values are reached through their parent item
+		if _, isProperty := element.Attr(syntax.prop); isProperty && element.Parent().Closest("["+syntax.scope+"]").Length() > 0 {
+			return
+		}
+		items = append(items, *buildItem(element, syntax, baseURL))
+	})
+	return items
+}
+
+// buildItem reads the type, identifier and properties of an item element
+func buildItem(element *goquery.Selection, syntax itemSyntax, baseURL *url.URL) *types.StructuredItem {
+	item := &types.StructuredItem{
+		Type:       strings.Fields(element.AttrOr(syntax.types, "")),
+		ID:         strings.TrimSpace(element.AttrOr(syntax.id, "")),
+		Properties: make(map[string][]any),
+	}
+
+	scope := element.Get(0)
+	element.Find("[" + syntax.prop + "]").Each(func(i int, property *goquery.Selection) {
+		owner := property.Parent().Closest("[" + syntax.scope + "]")
+		if owner.Length() == 0 || owner.Get(0) != scope {
+			return
+		}
+
+		var value any
+		if _, nested := property.Attr(syntax.scope); nested {
+			value = buildItem(property, syntax, baseURL)
+		} else {
+			value = itemPropertyValue(property, baseURL)
+		}
+		for _, name := range strings.Fields(property.AttrOr(syntax.prop, "")) {
+			item.Properties[name] = append(item.Properties[name], value)
+		}
+	})
+	return item
+}
+
+// itemPropertyValue returns the value of a property element following the Microdata rules,
+// which also cover the content, href, src and datetime attributes used by RDFa
+func itemPropertyValue(property *goquery.Selection, baseURL *url.URL) string {
+	if content, ok := property.Attr("content"); ok {
+		return strings.TrimSpace(content)
+	}
+
+	var attribute string
+	switch goquery.NodeName(property) {
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		attribute = "src"
+	case "a", "area", "link":
+		attribute = "href"
+	case "object":
+		attribute = "data"
+	case "data", "meter":
+		return strings.TrimSpace(property.AttrOr("value", ""))
+	case "time":
+		if datetime, ok := property.Attr("datetime"); ok {
+			return strings.TrimSpace(datetime)
+		}
+	}
+	if attribute != "" {
+		value := strings.TrimSpace(property.AttrOr(attribute, ""))
+		if resolved, err := baseURL.Parse(value); err == nil && value != "" {
+			return resolved.String()
+		}
+		return value
+	}
+	return strings.Join(strings.Fields(itemText(property.Get(0))), " ")
+}
+
+// itemText returns the text of a node without the contents of scripts and styles
+func itemText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	if node.Type == html.ElementNode && (node.Data == "script" || node.Data == "style") {
+		return ""
+	}
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(itemText(child))
+		text.WriteString(" ")
+	}
+	return text.String()
+}
+
+// itemString returns the first string value of a property in a list of items or their nested items
+func itemString(items []types.StructuredItem, name string) string {
+	for _, item := range items {
+		if value := findItemString(&item, name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// findItemString searches an item and its nested items for a string property
+func findItemString(item *types.StructuredItem, name string) string {
+	for _, value := range item.Properties[name] {
+		if text, ok := value.(string); ok && text != "" {
+			return text
+		}
+	}
+	for _, values := range item.Properties {
+		for _, value := range values {
+			if nested, ok := value.(*types.StructuredItem); ok {
+				if text := findItemString(nested, name); text != "" {
+					return text
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// firstMetaContent returns the content of the first matching meta tag
+func firstMetaContent(doc *goquery.Document, selectors []string) string {
+	for _, selector := range selectors {
+		if content := strings.TrimSpace(doc.Find(selector).AttrOr("content", "")); content != "" {
+			return content
+		}
+	}
+	return ""
+}
+
+// normalizeStructuredDate converts a date to RFC 3339 in UTC, keeping unparseable values as they are
+func normalizeStructuredDate(value string) string {
+	if formatted := formatSitemapDate(value); formatted != "" {
+		return formatted
+	}
+	return value
+}
+
+// formatStructuredData formats structured data for display
+func formatStructuredData(data *types.StructuredData) string {
+	var resultText string
+	if data.CanonicalURL != "" {
+		resultText += fmt.Sprintf("Canonical URL: %s\n", data.CanonicalURL)
+	}
+	if data.SiteName != "" {
+		resultText += fmt.Sprintf("Site Name: %s\n", data.SiteName)
+	}
+	if data.PublishedTime != "" {
+		resultText += fmt.Sprintf("Published: %s\n", data.PublishedTime)
+	}
+	if data.ModifiedTime != "" {
+		resultText += fmt.Sprintf("Modified: %s\n", data.ModifiedTime)
+	}
+
+	sections := []struct {
+		name  string
+		value any
+		empty bool
+	}{
+		{"OpenGraph", data.OpenGraph, len(data.OpenGraph) == 0},
+		{"Twitter Card", data.TwitterCard, len(data.TwitterCard) == 0},
+		{"JSON-LD", data.JSONLD, len(data.JSONLD) == 0},
+		{"Microdata", data.Microdata, len(data.Microdata) == 0},
+		{"RDFa", data.RDFa, len(data.RDFa) == 0},
+	}
+	for _, section := range sections {
+		if section.empty {
+			continue
+		}
+		var encoded strings.Builder
+		encoder := json.NewEncoder(&encoded)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.value); err != nil {
+			continue
+		}
+		resultText += fmt.Sprintf("%s:\n%s", section.name, encoded.String())
+	}
+
+	for _, dataErr := range data.Errors {
+		resultText += fmt.Sprintf("Error: %s\n", dataErr)
+	}
+	if resultText == "" {
+		resultText = "No structured data found\n"
+	}
+	return resultText
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"log"
+	"net/url"
+
+	"ez-web-search/internal/config"
+	"ez-web-search/internal/policy"
+	"ez-web-search/pkg/types"
+)
+
+// newPolicyEngine builds the URL policy engine. An invalid policy, which Config.Validate
+// reports at startup, denies every URL instead of silently allowing them.
+func newPolicyEngine(cfg *config.Config) *policy.Engine {
+	rules, err := cfg.Policy.LoadRules()
+	if err == nil {
+		var engine *policy.Engine
+		if engine, err = policy.New(cfg.Policy.DefaultAction, rules); err == nil {
+			return engine
+		}
+	}
+	log.Printf("URL policy: %v, denying all URLs", err)
+	engine, _ := policy.New(string(policy.Deny), nil)
+	return engine
+}
+
+// applyURLPolicy removes search results whose link the policy denies or that cannot be parsed,
+// and flags those that require confirmation; it returns the kept results and the number removed
+func applyURLPolicy(engine *policy.Engine, results []types.SearchResult) ([]types.SearchResult, int) {
+	kept := make([]types.SearchResult, 0, len(results))
+	for _, result := range results {
+		link, err := url.Parse(result.Link)
+		if err != nil {
+			continue
+		}
+		switch engine.Evaluate(link).Action {
+		case policy.Deny:
+			continue
+		case policy.Confirm:
+			result.RequiresConfirmation = true
+		}
+		kept = append(kept, result)
+	}
+	return kept, len(results) - len(kept)
+}
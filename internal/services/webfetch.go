@@ -2,43 +2,248 @@ package services
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/http/httpproxy"
 
 	"ez-web-search/internal/config"
+	"ez-web-search/internal/httpcache"
+	"ez-web-search/internal/policy"
 	"ez-web-search/internal/utils"
 	"ez-web-search/pkg/types"
 )
 
+// Output formats supported by FetchWebPage
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Link styles supported by the Markdown output format
+const (
+	LinkStyleInline    = "inline"
+	LinkStyleReference = "reference"
+)
+
 // WebFetchService handles web page fetching and content extraction
 type WebFetchService struct {
 	config     *config.Config
 	httpClient *http.Client
 	antiBot    *utils.AntiBotManager
+	documents  *documentCache
+	handlers   *ContentHandlerRegistry
+	policy     *policy.Engine
+	robots     *robotsCache
+	scheduler  *hostScheduler
 }
 
 // NewWebFetchService creates a new web fetch service
 func NewWebFetchService(cfg *config.Config) *WebFetchService {
-	return &WebFetchService{
+	s := &WebFetchService{
 		config: cfg,
 		httpClient: &http.Client{
-			Timeout: cfg.WebFetch.Timeout,
+			Timeout:   cfg.WebFetch.Timeout,
+			Transport: newFetchTransport(cfg.WebFetch),
 		},
-		antiBot: utils.NewAntiBotManager(cfg.UserAgent.Pool),
+		antiBot:   utils.NewAntiBotManager(cfg.UserAgent.Pool),
+		documents: newDocumentCache(cfg.WebFetch.DocumentCacheTTL, cfg.WebFetch.DocumentCacheSize),
+		policy:    newPolicyEngine(cfg),
+		robots:    newRobotsCache(cfg.WebFetch.Polite.RobotsCacheTTL),
+		scheduler: newHostScheduler(cfg.WebFetch.Scheduler.MaxConcurrent, cfg.WebFetch.Scheduler.MaxPerHost),
+	}
+	s.httpClient.CheckRedirect = s.checkRedirect
+	s.handlers = s.newContentHandlers()
+	return s
+}
+
+// hostInterval returns the minimum time between two requests to the same host: the configured
+// interval or the crawl delay, whichever is longer, varied randomly unless in polite mode
+func (s *WebFetchService) hostInterval(crawlDelay time.Duration) time.Duration {
+	interval := max(s.config.WebFetch.Scheduler.MinInterval, crawlDelay)
+	if s.config.WebFetch.UserAgentRotate && !s.config.WebFetch.Polite.Enabled {
+		interval = max(interval, s.antiBot.GetRandomDelay(s.config.WebFetch.DelayMin, s.config.WebFetch.DelayMax))
 	}
+	return interval
 }
 
-// FetchWebPage fetches and extracts content from a web page with anti-bot measures
+// maxHostWait returns how long a request may wait for its host's interval, 0 for no limit
+func (s *WebFetchService) maxHostWait() time.Duration {
+	if s.config.WebFetch.Polite.Enabled {
+		return s.config.WebFetch.Polite.MaxWait
+	}
+	return 0
+}
+
+// waitForRetry sleeps for the retry delay of an attempt and then for the interval of the
+// target's host, so that a retry is spaced out like any other request to it
+func (s *WebFetchService) waitForRetry(ctx context.Context, target *url.URL, crawlDelay time.Duration, resp *http.Response, attempt int) error {
+	if err := sleepContext(ctx, s.antiBot.GetRetryDelay(resp, attempt)); err != nil {
+		return err
+	}
+	return s.scheduler.Wait(ctx, strings.ToLower(target.Host), s.hostInterval(crawlDelay), s.maxHostWait())
+}
+
+// newFetchTransport builds the transport of the web fetch client: the SSRF guard when enabled,
+// wrapped in an HTTP cache when caching is enabled
+func newFetchTransport(cfg config.WebFetchConfig) http.RoundTripper {
+	transport := http.DefaultTransport
+	if cfg.SSRF.Enabled {
+		transport = newGuardedTransport(cfg.SSRF)
+	}
+	if !cfg.Cache.Enabled {
+		return transport
+	}
+
+	var store httpcache.Store
+	if cfg.Cache.Store == "disk" {
+		diskStore, err := httpcache.NewDiskStore(cfg.Cache.Dir, cfg.Cache.MaxEntries, cfg.Cache.MaxBytes)
+		if err != nil {
+			log.Printf("HTTP cache: %v, falling back to memory store", err)
+		} else {
+			store = diskStore
+		}
+	}
+	if store == nil {
+		store = httpcache.NewMemoryStore(cfg.Cache.MaxEntries, cfg.Cache.MaxBytes)
+	}
+
+	cache := httpcache.NewTransport(transport, store, cfg.Cache.MaxEntrySize)
+	cache.MaxStaleOnError = cfg.Cache.MaxStaleOnError
+	return cache
+}
+
+// newGuardedTransport returns a transport that connects only to permitted addresses.
+// Every connection, including those of redirect hops, is dialed through the guard.
+func newGuardedTransport(cfg config.SSRFConfig) http.RoundTripper {
+	guard, err := utils.NewAddressGuard(cfg.BlockedCIDRs, cfg.AllowedCIDRs, cfg.AllowedHosts)
+	if err != nil {
+		log.Printf("SSRF protection: %v, using the built-in ranges only", err)
+		guard, _ = utils.NewAddressGuard(nil, nil, cfg.AllowedHosts)
+	}
+
+	// A proxy resolves destinations itself, so the proxy is trusted and each destination
+	// is checked before the request is handed to it
+	proxyConfig := httpproxy.FromEnvironment()
+	for _, proxy := range []string{proxyConfig.HTTPProxy, proxyConfig.HTTPSProxy} {
+		if proxyURL, err := url.Parse(proxy); err == nil && proxyURL.Hostname() != "" {
+			guard.AllowHost(proxyURL.Hostname())
+		}
+	}
+	proxyFunc := proxyConfig.ProxyFunc()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = guard.DialContext
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := proxyFunc(req.URL)
+		if err != nil || proxyURL == nil {
+			return proxyURL, err
+		}
+		if host := req.URL.Hostname(); !guard.HostAllowed(host) {
+			if _, err := guard.Resolve(req.Context(), "tcp", host); err != nil {
+				return nil, err
+			}
+		}
+		return proxyURL, nil
+	}
+	return transport
+}
+
+// FetchWebPage fetches and extracts content from a web page with anti-bot measures and
+// returns the chunk of content selected by opts.StartIndex and opts.MaxLength.
+// Follow-up chunks are served from the document cache instead of re-downloading the page.
 func (s *WebFetchService) FetchWebPage(ctx context.Context, opts types.WebFetchOptions) (*types.WebPageContent, error) {
+	if opts.Format == "" {
+		opts.Format = FormatText
+	}
+
+	// Check the policy before serving follow-up chunks from the document cache
+	parsedURL, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := s.policy.Check(parsedURL, opts.Confirm); err != nil {
+		return nil, err
+	}
+	key := documentCacheKey(opts)
+
+	var content *types.WebPageContent
+	if opts.StartIndex > 0 {
+		if cached, ok := s.documents.Get(key); ok {
+			content = cached
+			content.CacheStatus = DocumentCacheHit
+		}
+	}
+
+	if content == nil {
+		fetched, err := s.fetchDocument(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		s.documents.Put(key, fetched)
+		content = fetched
+	}
+
+	maxLength := opts.MaxLength
+	if maxLength <= 0 {
+		maxLength = s.config.WebFetch.MaxContentSize
+	}
+	paginateContent(content, opts.StartIndex, maxLength)
+	return content, nil
+}
+
+// fetchDocument downloads a web page and extracts its full content
+func (s *WebFetchService) fetchDocument(ctx context.Context, opts types.WebFetchOptions) (*types.WebPageContent, error) {
+	fetched, err := s.Fetch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &types.WebPageContent{
+		URL:         opts.URL,
+		FinalURL:    fetched.URL.String(),
+		Redirects:   fetched.Redirects,
+		StatusCode:  fetched.StatusCode,
+		ContentType: fetched.ContentType,
+		CacheStatus: fetched.Header.Get(httpcache.StatusHeader),
+		Format:      opts.Format,
+		Truncated:   fetched.Truncated,
+		Headers:     make(map[string]string),
+	}
+
+	// Extract basic headers
+	for key, values := range fetched.Header {
+		if len(values) > 0 {
+			content.Headers[key] = values[0]
+		}
+	}
+
+	// Selectors and XPath queries address HTML documents only
+	if (opts.Selector != "" || opts.XPath != "") && fetched.MediaType != "text/html" && fetched.MediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("selector and xpath can only be applied to HTML documents, not %s", fetched.MediaType)
+	}
+
+	// Dispatch on the media type instead of assuming every response is HTML
+	if err := s.handlers.Lookup(fetched.MediaType).Extract(fetched, content); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// Fetch downloads a URL through the anti-bot, caching and decoding HTTP stack and returns the
+// decoded body without extracting it
+func (s *WebFetchService) Fetch(ctx context.Context, opts types.WebFetchOptions) (*FetchedDocument, error) {
 	// Validate URL
 	parsedURL, err := url.Parse(opts.URL)
 	if err != nil {
@@ -50,15 +255,33 @@ func (s *WebFetchService) FetchWebPage(ctx context.Context, opts types.WebFetchO
 		return nil, fmt.Errorf("unsupported URL scheme: %s", parsedURL.Scheme)
 	}
 
-	// Apply random delay to avoid detection
-	if s.config.WebFetch.UserAgentRotate && s.antiBot.ShouldDelay() {
-		delay := s.antiBot.GetRandomDelay(s.config.WebFetch.DelayMin, s.config.WebFetch.DelayMax)
-		time.Sleep(delay)
+	// Refuse URLs denied by the policy, or requiring a confirmation the caller did not give
+	if err := s.policy.Check(parsedURL, opts.Confirm); err != nil {
+		return nil, err
 	}
+	if opts.Confirm {
+		ctx = policy.WithConfirmation(ctx, parsedURL)
+	}
+
+	// In polite mode, honour robots.txt and the host's crawl delay
+	crawlDelay, err := s.checkRobots(ctx, parsedURL, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait for a free connection and for the host's interval since its previous request;
+	// requests to other hosts are not held up
+	release, err := s.scheduler.Acquire(ctx, strings.ToLower(parsedURL.Host), s.hostInterval(crawlDelay), s.maxHostWait())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
-	// Create request with random timeout variance
+	// Create request with random timeout variance; its redirects are checked and recorded
+	// by checkRedirect
+	redirects := &redirectTracker{settings: s.redirectSettingsFor(opts)}
 	timeout := s.antiBot.GetRandomTimeout(s.config.WebFetch.Timeout)
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	ctxWithTimeout, cancel := context.WithTimeout(withRedirectTracker(ctx, redirects), timeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctxWithTimeout, "GET", opts.URL, nil)
@@ -66,27 +289,47 @@ func (s *WebFetchService) FetchWebPage(ctx context.Context, opts types.WebFetchO
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Use provided user agent or get a random one
-	userAgent := opts.UserAgent
-	if userAgent == "" {
-		userAgent = s.antiBot.GetRandomUserAgent()
+	if s.config.WebFetch.Polite.Enabled {
+		// Identify as what we are instead of posing as a browser
+		req.Header.Set("User-Agent", s.config.WebFetch.Polite.UserAgent)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		req.Header.Set("Accept-Encoding", utils.AcceptEncoding)
+	} else {
+		// Use provided user agent or get a random one
+		userAgent := opts.UserAgent
+		if userAgent == "" {
+			userAgent = s.antiBot.GetRandomUserAgent()
+		}
+
+		// Set realistic headers to avoid bot detection
+		s.antiBot.SetRealisticHeaders(req, userAgent)
 	}
 
-	// Set realistic headers to avoid bot detection
-	s.antiBot.SetRealisticHeaders(req, userAgent)
+	// The simulated browser reload (Cache-Control: max-age=0) would make the HTTP cache
+	// revalidate a random subset of requests, so leave freshness decisions to the cache
+	if s.config.WebFetch.Cache.Enabled {
+		req.Header.Del("Cache-Control")
+	}
 
 	// Perform request with retry logic
 	var resp *http.Response
 	maxRetries := 3
 	for attempt := 1; attempt <= maxRetries; attempt++ {
+		redirects.hops = nil
 		resp, err = s.httpClient.Do(req)
 		if err != nil {
+			// Blocked destinations and policy, robots.txt or redirect refusals stay refused, so there is nothing to retry
+			if errors.Is(err, utils.ErrBlockedAddress) || errors.Is(err, policy.ErrDenied) || errors.Is(err, policy.ErrConfirmationRequired) ||
+				errors.Is(err, utils.ErrDisallowedByRobots) || errors.Is(err, ErrRedirectRefused) {
+				return nil, fmt.Errorf("failed to fetch page: %w", err)
+			}
 			if attempt == maxRetries {
 				return nil, fmt.Errorf("failed to fetch page after %d attempts: %w", maxRetries, err)
 			}
 			// Wait before retry
-			retryDelay := s.antiBot.GetRetryDelay(resp, attempt)
-			time.Sleep(retryDelay)
+			if err := s.waitForRetry(ctx, parsedURL, crawlDelay, resp, attempt); err != nil {
+				return nil, fmt.Errorf("failed to fetch page: %w", err)
+			}
 			continue
 		}
 
@@ -96,8 +339,9 @@ func (s *WebFetchService) FetchWebPage(ctx context.Context, opts types.WebFetchO
 			if attempt == maxRetries {
 				return nil, fmt.Errorf("request was rate limited after %d attempts", maxRetries)
 			}
-			retryDelay := s.antiBot.GetRetryDelay(resp, attempt)
-			time.Sleep(retryDelay)
+			if err := s.waitForRetry(ctx, parsedURL, crawlDelay, resp, attempt); err != nil {
+				return nil, fmt.Errorf("request was rate limited: %w", err)
+			}
 			continue
 		}
 
@@ -112,65 +356,73 @@ func (s *WebFetchService) FetchWebPage(ctx context.Context, opts types.WebFetchO
 	}
 	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	// Read and decode the response body, aborting once it exceeds the size limit.
+	// With partial bodies allowed, the first MaxBodySize bytes are parsed instead.
+	body, err := utils.ReadResponseBody(resp, s.config.WebFetch.MaxBodySize, s.config.WebFetch.AllowPartialBody)
+	truncated := false
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// Check if response is gzip compressed and decompress if needed
-	var reader io.Reader = bytes.NewReader(body)
-	if strings.Contains(resp.Header.Get("Content-Encoding"), "gzip") || len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
-		gzipReader, err := gzip.NewReader(bytes.NewReader(body))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
-		}
-		defer gzipReader.Close()
-		decompressed, err := io.ReadAll(gzipReader)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decompress response: %w", err)
+		if !errors.Is(err, utils.ErrContentTooLarge) || !s.config.WebFetch.AllowPartialBody || len(body) == 0 {
+			return nil, err
 		}
-		reader = bytes.NewReader(decompressed)
+		truncated = true
 	}
 
-	// Parse HTML
-	doc, err := goquery.NewDocumentFromReader(reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
-	}
-
-	// Extract content
-	content := &types.WebPageContent{
-		URL:         opts.URL,
+	servedURL := finalURL(resp, parsedURL)
+	return &FetchedDocument{
+		URL:         servedURL,
+		Redirects:   redirects.hops,
 		StatusCode:  resp.StatusCode,
+		Header:      resp.Header,
+		MediaType:   resolveMediaType(resp.Header.Get("Content-Type"), servedURL, body),
 		ContentType: resp.Header.Get("Content-Type"),
-		Headers:     make(map[string]string),
+		Body:        body,
+		Truncated:   truncated,
+		Options:     opts,
+	}, nil
+}
+
+// extractHTML parses an HTML document and extracts its metadata, main content, links and images
+func (s *WebFetchService) extractHTML(fetched *FetchedDocument, content *types.WebPageContent) error {
+	// Detect the character encoding and transcode to UTF-8 before parsing
+	body, err := decodeText(fetched, content)
+	if err != nil {
+		return err
 	}
 
-	// Extract basic headers
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			content.Headers[key] = values[0]
-		}
+	// Parse HTML
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
 	// Extract metadata
 	s.extractMetadata(doc, content)
 
-	// Extract main content
-	s.extractContent(doc, content)
+	// Extract the nodes targeted by a selector or XPath query, or else the main content
+	if fetched.Options.Selector != "" || fetched.Options.XPath != "" {
+		if err := extractMatches(doc, fetched, content); err != nil {
+			return err
+		}
+	} else {
+		s.extractContent(doc, content, content.Format, fetched.Options.LinkStyle, fetched.URL)
+	}
+
+	// Extract structured data if requested
+	if fetched.Options.IncludeStructuredData {
+		content.StructuredData = extractStructuredData(doc, fetched.URL)
+	}
 
 	// Extract links if requested
-	if opts.IncludeLinks {
-		s.extractLinks(doc, content, parsedURL)
+	if fetched.Options.IncludeLinks {
+		s.extractLinks(doc, content, fetched.URL)
 	}
 
 	// Extract images if requested
-	if opts.IncludeImages {
-		s.extractImages(doc, content, parsedURL)
+	if fetched.Options.IncludeImages {
+		s.extractImages(doc, content, fetched.URL)
 	}
 
-	return content, nil
+	return nil
 }
 
 // extractMetadata extracts metadata from the HTML document
@@ -201,57 +453,46 @@ func (s *WebFetchService) extractMetadata(doc *goquery.Document, content *types.
 	}
 }
 
-// extractContent extracts main content from the HTML document
-func (s *WebFetchService) extractContent(doc *goquery.Document, content *types.WebPageContent) {
-	var textContent strings.Builder
-
-	// Try common content selectors in order of preference
-	contentSelectors := []string{
-		"article", "main", ".content", "#content", ".post-content",
-		".entry-content", ".article-content", ".post-body", ".article-body",
-		"[role='main']", ".main-content", "#main-content",
-	}
-
-	for _, selector := range contentSelectors {
-		doc.Find(selector).Each(func(i int, s *goquery.Selection) {
-			text := strings.TrimSpace(s.Text())
-			if len(text) > 50 { // Only include substantial text blocks
-				textContent.WriteString(text)
-				textContent.WriteString("\n\n")
-			}
-		})
-		if textContent.Len() > 500 { // Stop if we have enough content
-			break
-		}
-	}
-
-	// If no content found, extract paragraphs
-	if textContent.Len() < 100 {
-		doc.Find("p").Each(func(i int, s *goquery.Selection) {
-			text := strings.TrimSpace(s.Text())
-			if len(text) > 30 {
-				textContent.WriteString(text)
-				textContent.WriteString("\n\n")
-			}
+// extractContent extracts main content from the HTML document in the requested format
+func (s *WebFetchService) extractContent(doc *goquery.Document, content *types.WebPageContent, format, linkStyle string, baseURL *url.URL) {
+	mainContent := s.selectMainContent(doc)
+
+	switch format {
+	case FormatMarkdown:
+		content.Content = htmlToMarkdown(mainContent.Nodes, baseURL, linkStyle)
+	case FormatHTML:
+		content.Content = selectionHTML(mainContent)
+	default:
+		var textContent strings.Builder
+		mainContent.Each(func(i int, s *goquery.Selection) {
+			textContent.WriteString(strings.TrimSpace(s.Text()))
+			textContent.WriteString("\n\n")
 		})
-	}
+		content.Content = strings.TrimSpace(textContent.String())
 
-	// If still no content, extract all text from body
-	if textContent.Len() < 50 {
-		bodyText := strings.TrimSpace(doc.Find("body").Text())
-		textContent.WriteString(bodyText)
+		// Clean up content (remove excessive whitespace)
+		re := regexp.MustCompile(`\s+`)
+		content.Content = re.ReplaceAllString(content.Content, " ")
 	}
+}
 
-	content.Content = strings.TrimSpace(textContent.String())
-
-	// Clean up content (remove excessive whitespace)
-	re := regexp.MustCompile(`\s+`)
-	content.Content = re.ReplaceAllString(content.Content, " ")
+// selectMainContent returns the elements holding the main content of the HTML document
+func (s *WebFetchService) selectMainContent(doc *goquery.Document) *goquery.Selection {
+	return extractReadableContent(doc)
+}
 
-	// Limit content length
-	if len(content.Content) > s.config.WebFetch.MaxContentSize {
-		content.Content = content.Content[:s.config.WebFetch.MaxContentSize] + "..."
-	}
+// selectionHTML returns the outer HTML of each selected element without scripts and styles
+func selectionHTML(selection *goquery.Selection) string {
+	var htmlContent strings.Builder
+	selection.Each(func(i int, s *goquery.Selection) {
+		clone := s.Clone()
+		clone.Find("script, style, noscript, template").Remove()
+		if outer, err := goquery.OuterHtml(clone); err == nil {
+			htmlContent.WriteString(strings.TrimSpace(outer))
+			htmlContent.WriteString("\n\n")
+		}
+	})
+	return strings.TrimSpace(htmlContent.String())
 }
 
 // extractLinks extracts links from the HTML document
@@ -294,8 +535,41 @@ func (s *WebFetchService) extractImages(doc *goquery.Document, content *types.We
 func (s *WebFetchService) FormatWebPageContent(content *types.WebPageContent, includeLinks, includeImages bool) string {
 	var resultText string
 	resultText += fmt.Sprintf("Web Page Content for: %s\n", content.URL)
+	if content.FinalURL != "" && content.FinalURL != content.URL {
+		resultText += fmt.Sprintf("Final URL: %s\n", content.FinalURL)
+	}
+	if len(content.Redirects) > 0 {
+		resultText += fmt.Sprintf("Redirects: %d\n", len(content.Redirects))
+		for i, hop := range content.Redirects {
+			resultText += fmt.Sprintf("   %d. %d %s -> %s\n", i+1, hop.StatusCode, hop.URL, hop.Location)
+		}
+	}
 	resultText += fmt.Sprintf("Status Code: %d\n", content.StatusCode)
-	resultText += fmt.Sprintf("Content Type: %s\n\n", content.ContentType)
+	resultText += fmt.Sprintf("Content Type: %s\n", content.ContentType)
+	if content.Charset != "" {
+		resultText += fmt.Sprintf("Charset: %s (%s)\n", content.Charset, content.CharsetSource)
+	}
+	if content.CacheStatus != "" {
+		resultText += fmt.Sprintf("Cache: %s\n", content.CacheStatus)
+	}
+	if content.Truncated {
+		resultText += fmt.Sprintf("Body Truncated: only the first %d bytes of the page were parsed\n", s.config.WebFetch.MaxBodySize)
+	}
+	if content.Matches != nil || content.MatchCount > 0 {
+		resultText += fmt.Sprintf("Matches: %d", content.MatchCount)
+		if len(content.Matches) < content.MatchCount {
+			resultText += fmt.Sprintf(" (showing %d; set all_matches to return every match)", len(content.Matches))
+		}
+		resultText += "\n"
+	}
+	if content.TotalLength > 0 {
+		end := content.StartIndex + utf8.RuneCountInString(content.Content)
+		resultText += fmt.Sprintf("Content Range: characters %d-%d of %d\n", content.StartIndex, end, content.TotalLength)
+		if content.NextIndex > 0 {
+			resultText += fmt.Sprintf("More content available: call again with start_index=%d\n", content.NextIndex)
+		}
+	}
+	resultText += "\n"
 
 	if content.Title != "" {
 		resultText += fmt.Sprintf("Title: %s\n\n", content.Title)
@@ -309,6 +583,14 @@ func (s *WebFetchService) FormatWebPageContent(content *types.WebPageContent, in
 		resultText += fmt.Sprintf("Author: %s\n", content.Author)
 	}
 
+	if content.CreatedAt != "" {
+		resultText += fmt.Sprintf("Created: %s\n", content.CreatedAt)
+	}
+
+	if content.PageCount > 0 {
+		resultText += fmt.Sprintf("Pages: %d\n", content.PageCount)
+	}
+
 	if content.Language != "" {
 		resultText += fmt.Sprintf("Language: %s\n", content.Language)
 	}
@@ -317,12 +599,20 @@ func (s *WebFetchService) FormatWebPageContent(content *types.WebPageContent, in
 		resultText += fmt.Sprintf("Keywords: %s\n", content.Keywords)
 	}
 
-	if content.Author != "" || content.Language != "" || content.Keywords != "" {
+	if content.Author != "" || content.CreatedAt != "" || content.PageCount > 0 || content.Language != "" || content.Keywords != "" {
 		resultText += "\n"
 	}
 
 	if content.Content != "" {
-		resultText += fmt.Sprintf("Content:\n%s\n\n", content.Content)
+		if content.Format != "" && content.Format != FormatText {
+			resultText += fmt.Sprintf("Content (%s):\n%s\n\n", content.Format, content.Content)
+		} else {
+			resultText += fmt.Sprintf("Content:\n%s\n\n", content.Content)
+		}
+	}
+
+	if content.StructuredData != nil {
+		resultText += fmt.Sprintf("Structured Data:\n%s\n", formatStructuredData(content.StructuredData))
 	}
 
 	if includeLinks && len(content.Links) > 0 {
@@ -1,132 +1,309 @@
 package services
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
-	"time"
+	"sync"
 
 	"ez-web-search/internal/config"
+	"ez-web-search/internal/policy"
 	"ez-web-search/internal/utils"
 	"ez-web-search/pkg/types"
 )
 
-// WebSearchService handles web search operations
+// WebSearchService routes web search requests to registered search providers
 type WebSearchService struct {
-	config     *config.Config
-	httpClient *http.Client
-	antiBot    *utils.AntiBotManager
+	config    *config.Config
+	providers *ProviderRegistry
+	health    *HealthTracker
+	cache     *SearchCache
+	policy    *policy.Engine
 }
 
-// NewWebSearchService creates a new web search service
+// NewWebSearchService creates a new web search service with the configured providers registered
 func NewWebSearchService(cfg *config.Config) *WebSearchService {
-	return &WebSearchService{
-		config: cfg,
-		httpClient: &http.Client{
+	s := &WebSearchService{
+		config:    cfg,
+		providers: NewProviderRegistry(),
+		health:    NewHealthTracker(cfg.Search.Health),
+		cache:     NewSearchCache(cfg.Search.CacheTTL, cfg.Search.CacheSize),
+		policy:    newPolicyEngine(cfg),
+	}
+
+	// BigModel engines are only available when a token is configured
+	if cfg.BigModel.Token != "" {
+		httpClient := &http.Client{
 			Timeout: cfg.BigModel.Timeout,
-		},
-		antiBot: utils.NewAntiBotManager(cfg.UserAgent.Pool),
+		}
+		antiBot := utils.NewAntiBotManager(cfg.UserAgent.Pool)
+		for _, engine := range BigModelEngines {
+			s.providers.Register(NewBigModelProvider(cfg, engine, httpClient, antiBot))
+		}
+	}
+
+	// SearXNG is registered when a self-hosted instance is configured
+	if cfg.SearXNG.BaseURL != "" {
+		s.providers.Register(NewSearXNGProvider(cfg))
+	}
+
+	return s
+}
+
+// RegisterProvider makes an additional search provider available by name
+func (s *WebSearchService) RegisterProvider(provider SearchProvider) error {
+	return s.providers.Register(provider)
+}
+
+// HasProvider reports whether a provider is registered under name
+func (s *WebSearchService) HasProvider(name string) bool {
+	_, ok := s.providers.Get(name)
+	return ok
+}
+
+// ProviderNames returns the names of all registered providers
+func (s *WebSearchService) ProviderNames() []string {
+	return s.providers.Names()
+}
+
+// ProviderHealth returns the current health of the named provider
+func (s *WebSearchService) ProviderHealth(name string) ProviderHealthStatus {
+	return s.health.Status(name)
+}
+
+// Search performs a web search, serving repeated queries from the cache and
+// coalescing identical in-flight queries into a single upstream call
+func (s *WebSearchService) Search(ctx context.Context, opts types.WebSearchOptions) (*types.SearchResponse, error) {
+	key := searchCacheKey(opts, s.config.Search.DefaultEngine)
+	return s.cache.GetOrSearch(ctx, key, func(ctx context.Context) (*types.SearchResponse, error) {
+		return s.searchUncached(ctx, opts)
+	})
+}
+
+// searchUncached performs a web search and applies the global blocklist, the domain filters
+// in opts and the URL policy
+func (s *WebSearchService) searchUncached(ctx context.Context, opts types.WebSearchOptions) (*types.SearchResponse, error) {
+	exclude := append(append([]string{}, s.config.Search.BlockedDomains...), opts.ExcludeDomains...)
+	filter := NewDomainFilter(opts.IncludeDomains, exclude)
+
+	var resp *types.SearchResponse
+	var err error
+	if filter.Empty() {
+		resp, err = s.search(ctx, opts)
+	} else {
+		resp, err = s.searchFiltered(ctx, opts, filter)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp.SearchResult, resp.DeniedCount = applyURLPolicy(s.policy, resp.SearchResult)
+	return resp, nil
 }
 
-// Search performs a web search using BigModel API
-func (s *WebSearchService) Search(ctx context.Context, opts types.WebSearchOptions) (*types.WebSearchResponse, error) {
+// search performs an unfiltered search using the provider named by opts.SearchEngine,
+// or fans out to every provider in opts.SearchEngines and fuses their results
+func (s *WebSearchService) search(ctx context.Context, opts types.WebSearchOptions) (*types.SearchResponse, error) {
+	engines := s.expandEngines(opts.SearchEngines)
+	if len(engines) > 1 {
+		opts.SearchEngines = engines
+		return s.searchFanOut(ctx, opts)
+	}
+	if len(engines) == 1 {
+		opts.SearchEngine = engines[0]
+	}
+
 	// Use provided search engine or fall back to config default
 	searchEngine := opts.SearchEngine
 	if searchEngine == "" {
-		searchEngine = s.config.BigModel.SearchEngine
+		searchEngine = s.config.Search.DefaultEngine
 	}
 
-	reqBody := types.WebSearchRequest{
-		SearchQuery:  opts.Query,
-		SearchEngine: searchEngine,
-		SearchIntent: opts.SearchIntent,
+	if _, ok := s.providers.Get(searchEngine); !ok {
+		return nil, fmt.Errorf("unknown search provider: %s", searchEngine)
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	return s.searchWithFailover(ctx, opts, s.failoverChain(searchEngine))
+}
+
+// searchWithFailover tries each provider in chain until one answers, skipping providers in cooldown.
+// Only transient failures (5xx, rate limits, timeouts) move on to the next provider.
+func (s *WebSearchService) searchWithFailover(ctx context.Context, opts types.WebSearchOptions, chain []string) (*types.SearchResponse, error) {
+	// If every provider is cooling down, try them anyway rather than failing outright
+	candidates := make([]string, 0, len(chain))
+	for _, name := range chain {
+		if s.health.Available(name) {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = chain
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.config.BigModel.BaseURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	failures := make(map[string]string)
+	for _, name := range chain {
+		if !slices.Contains(candidates, name) {
+			failures[name] = "skipped: provider is cooling down after repeated failures"
+		}
 	}
 
-	// Set authentication and content type
-	req.Header.Set("Authorization", "Bearer "+s.config.BigModel.Token)
-	req.Header.Set("Content-Type", "application/json")
+	var lastErr error
+	for _, name := range candidates {
+		provider, _ := s.providers.Get(name)
+		resp, err := s.callProvider(ctx, provider, opts)
+		if err == nil {
+			if len(failures) > 0 {
+				resp.Errors = failures
+			}
+			return resp, nil
+		}
+
+		failures[name] = err.Error()
+		lastErr = err
+		if ctx.Err() != nil || !isFailoverError(err) {
+			return nil, err
+		}
+	}
 
-	// Set anti-bot headers
-	userAgent := s.antiBot.GetRandomUserAgent()
-	s.antiBot.SetRealisticHeaders(req, userAgent)
+	if len(failures) > 1 {
+		return nil, fmt.Errorf("all search providers failed: %s", formatProviderErrors(failures))
+	}
+	return nil, lastErr
+}
 
-	// Apply random delay if configured
-	if s.config.WebFetch.UserAgentRotate && s.antiBot.ShouldDelay() {
-		delay := s.antiBot.GetRandomDelay(s.config.WebFetch.DelayMin, s.config.WebFetch.DelayMax)
-		time.Sleep(delay)
+// failoverChain returns the requested provider followed by the configured failover providers
+func (s *WebSearchService) failoverChain(primary string) []string {
+	chain := []string{primary}
+	for _, name := range s.config.Search.FailoverEngines {
+		if _, ok := s.providers.Get(name); ok && !slices.Contains(chain, name) {
+			chain = append(chain, name)
+		}
 	}
+	return chain
+}
+
+// callProvider performs a single provider search and records the outcome in the health tracker
+func (s *WebSearchService) callProvider(ctx context.Context, provider SearchProvider, opts types.WebSearchOptions) (*types.SearchResponse, error) {
+	opts.SearchEngine = provider.Name()
+	opts.SearchEngines = nil
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := provider.Search(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		// Failures caused by our own caller going away say nothing about the provider
+		if ctx.Err() == nil && isFailoverError(err) {
+			s.health.RecordFailure(provider.Name(), err)
+		}
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// Check for rate limiting or blocking
-	if s.antiBot.IsRateLimited(resp) {
-		return nil, fmt.Errorf("request was rate limited, status: %d", resp.StatusCode)
-	}
+	s.health.RecordSuccess(provider.Name())
+	resp.Provider = provider.Name()
+	return resp, nil
+}
 
-	if s.antiBot.IsBlocked(resp) {
-		return nil, fmt.Errorf("request was blocked, status: %d", resp.StatusCode)
+// searchFanOut queries several providers concurrently and merges them with reciprocal-rank fusion
+func (s *WebSearchService) searchFanOut(ctx context.Context, opts types.WebSearchOptions) (*types.SearchResponse, error) {
+	engines := opts.SearchEngines
+	responses := make([]*types.SearchResponse, len(engines))
+	errs := make([]error, len(engines))
+
+	var wg sync.WaitGroup
+	for i, engine := range engines {
+		provider, ok := s.providers.Get(engine)
+		if !ok {
+			errs[i] = fmt.Errorf("unknown search provider: %s", engine)
+			continue
+		}
+		if !s.health.Available(engine) {
+			errs[i] = fmt.Errorf("skipped: provider is cooling down after repeated failures")
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, provider SearchProvider) {
+			defer wg.Done()
+			responses[i], errs[i] = s.callProvider(ctx, provider, opts)
+		}(i, provider)
 	}
+	wg.Wait()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	fused := &types.SearchResponse{
+		Provider: strings.Join(engines, ", "),
+	}
+	var succeeded []*types.SearchResponse
+	for i, engine := range engines {
+		if errs[i] != nil {
+			if fused.Errors == nil {
+				fused.Errors = make(map[string]string)
+			}
+			fused.Errors[engine] = errs[i].Error()
+			continue
+		}
+		succeeded = append(succeeded, responses[i])
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	if len(succeeded) == 0 {
+		return nil, fmt.Errorf("all search providers failed: %s", formatProviderErrors(fused.Errors))
 	}
 
-	// Check if response is gzip compressed
-	var reader io.Reader = bytes.NewReader(body)
-	if strings.Contains(resp.Header.Get("Content-Encoding"), "gzip") || len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
-		gzipReader, err := gzip.NewReader(bytes.NewReader(body))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	fused.SearchIntent = mergeSearchIntents(succeeded)
+	fused.SearchResult = fuseResults(succeeded, s.config.Search.FusionK, s.config.Search.FusionMaxResults)
+	return fused, nil
+}
+
+// expandEngines resolves the special name "all" and removes duplicate engine names
+func (s *WebSearchService) expandEngines(engines []string) []string {
+	var expanded []string
+	seen := make(map[string]bool)
+	for _, engine := range engines {
+		names := []string{engine}
+		if engine == "all" {
+			names = s.providers.Names()
 		}
-		defer gzipReader.Close()
-		decompressed, err := io.ReadAll(gzipReader)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decompress response: %w", err)
+		for _, name := range names {
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			expanded = append(expanded, name)
 		}
-		reader = bytes.NewReader(decompressed)
 	}
+	return expanded
+}
 
-	// Decode JSON response
-	var searchResp types.WebSearchResponse
-	if err := json.NewDecoder(reader).Decode(&searchResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+// formatProviderErrors renders provider errors in a stable order
+func formatProviderErrors(errs map[string]string) string {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return &searchResp, nil
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %s", name, errs[name]))
+	}
+	return strings.Join(parts, "; ")
 }
 
 // FormatSearchResponse formats the search response for display
-func (s *WebSearchService) FormatSearchResponse(resp *types.WebSearchResponse, query string, searchEngine string) string {
+func (s *WebSearchService) FormatSearchResponse(resp *types.SearchResponse, query string, searchEngine string) string {
 	var resultText string
 	resultText += fmt.Sprintf("Search Results for: %s\n", query)
 	resultText += fmt.Sprintf("Search Engine: %s\n", searchEngine)
-	resultText += fmt.Sprintf("Request ID: %s\n\n", resp.RequestID)
+	if resp.Provider != "" && resp.Provider != searchEngine {
+		resultText += fmt.Sprintf("Answered By: %s\n", resp.Provider)
+	}
+	if resp.RequestID != "" {
+		resultText += fmt.Sprintf("Request ID: %s\n", resp.RequestID)
+	}
+	if resp.Cached {
+		resultText += "Cache: hit\n"
+	}
+	resultText += "\n"
 
 	// Add search intent information if available
 	if len(resp.SearchIntent) > 0 {
@@ -153,11 +330,29 @@ func (s *WebSearchService) FormatSearchResponse(resp *types.WebSearchResponse, q
 			if result.Refer != "" {
 				resultText += fmt.Sprintf("   Source: %s\n", result.Refer)
 			}
+			if len(result.Engines) > 0 {
+				resultText += fmt.Sprintf("   Engines: %s\n", strings.Join(result.Engines, ", "))
+			}
+			if result.RequiresConfirmation {
+				resultText += "   Policy: fetching this URL requires the user's confirmation (confirm=true)\n"
+			}
 			resultText += "\n"
 		}
 	} else {
 		resultText += "No search results found.\n"
 	}
 
+	if resp.FilteredCount > 0 {
+		resultText += fmt.Sprintf("\nFiltered Results: %d removed by domain filters\n", resp.FilteredCount)
+	}
+
+	if resp.DeniedCount > 0 {
+		resultText += fmt.Sprintf("\nDenied Results: %d removed by the URL policy\n", resp.DeniedCount)
+	}
+
+	if len(resp.Errors) > 0 {
+		resultText += fmt.Sprintf("\nProvider Errors: %s\n", formatProviderErrors(resp.Errors))
+	}
+
 	return resultText
 }
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+)
+
+// ErrContentTooLarge is matched by errors.Is when a body exceeds its size limit
+var ErrContentTooLarge = errors.New("content too large")
+
+// ContentTooLargeError reports a body that exceeded the byte limit while being read
+type ContentTooLargeError struct {
+	// Limit is the maximum number of bytes allowed
+	Limit int64
+	// Decoded is true when the limit was hit after decompression
+	Decoded bool
+}
+
+func (e *ContentTooLargeError) Error() string {
+	stage := "response body"
+	if e.Decoded {
+		stage = "decompressed response body"
+	}
+	return fmt.Sprintf("content too large: %s exceeds the limit of %d bytes", stage, e.Limit)
+}
+
+// Is makes errors.Is(err, ErrContentTooLarge) succeed
+func (e *ContentTooLargeError) Is(target error) bool {
+	return target == ErrContentTooLarge
+}
+
+// limitedReader reads at most limit bytes and fails with ContentTooLargeError beyond that
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+	err       *ContentTooLargeError
+}
+
+// newLimitedReader returns a reader that yields the first limit bytes of r and then fails with
+// a ContentTooLargeError if r has more data; a non-positive limit never overflows
+func newLimitedReader(r io.Reader, limit int64, decoded bool) *limitedReader {
+	if limit <= 0 {
+		limit = math.MaxInt64
+	}
+	return &limitedReader{r: r, remaining: limit, err: &ContentTooLargeError{Limit: limit, Decoded: decoded}}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Probe for one more byte to tell an exact fit from an overflow
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			l.exceeded = true
+			return 0, l.err
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// ReadResponseBody reads and decodes a response body, enforcing maxBytes on the bytes received
+// and again on the decompressed bytes so that large downloads and decompression bombs are
+// aborted while streaming. On overflow it returns the bytes decoded so far together with a
+// ContentTooLargeError. Unless allowPartial is set, a declared Content-Length above the limit
+// fails before anything is read. A non-positive maxBytes disables both limits.
+func ReadResponseBody(resp *http.Response, maxBytes int64, allowPartial bool) ([]byte, error) {
+	if maxBytes > 0 && !allowPartial && resp.ContentLength > maxBytes {
+		return nil, &ContentTooLargeError{Limit: maxBytes}
+	}
+
+	// Decoders may wrap or replace the error of the underlying reader, so overflow is
+	// detected from the limited readers themselves
+	received := newLimitedReader(resp.Body, maxBytes, false)
+	decoder, err := NewDecodingReader(received, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	decoded := newLimitedReader(decoder, maxBytes, true)
+	body, err := io.ReadAll(decoded)
+	switch {
+	case received.exceeded:
+		return body, received.err
+	case decoded.exceeded:
+		return body, decoded.err
+	case err != nil:
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// Charset detection sources, in order of precedence
+const (
+	CharsetSourceBOM         = "bom"
+	CharsetSourceContentType = "content-type"
+	CharsetSourceMeta        = "meta"
+	CharsetSourceXML         = "xml-declaration"
+	CharsetSourceSniffed     = "sniffed"
+	CharsetSourceDefault     = "default"
+)
+
+// charsetPrescanLimit is how far into the document <meta> charset declarations are searched
+const charsetPrescanLimit = 4096
+
+// metaCharset matches both <meta charset="..."> and <meta http-equiv="Content-Type" content="...; charset=...">
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([A-Za-z0-9_:.+-]+)`)
+
+// xmlEncoding matches the encoding of an XML declaration: <?xml version="1.0" encoding="..."?>
+var xmlEncoding = regexp.MustCompile(`^\s*<\?xml[^>]+encoding\s*=\s*["']([A-Za-z0-9_:.+-]+)`)
+
+// sniffCandidates are the legacy multi-byte encodings tried when a document is not valid UTF-8
+var sniffCandidates = []string{"gb18030", "big5", "shift_jis", "euc-jp", "euc-kr"}
+
+// DetectedCharset describes the character encoding of a document
+type DetectedCharset struct {
+	Encoding encoding.Encoding
+	Name     string
+	Source   string
+}
+
+// DetectCharset determines the character encoding of an HTML, XML or text document from its
+// byte order mark, the Content-Type header, XML or <meta> declarations and finally byte sniffing
+func DetectCharset(body []byte, contentType string) DetectedCharset {
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		return lookupCharset("utf-8", CharsetSourceBOM)
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return lookupCharset("utf-16be", CharsetSourceBOM)
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return lookupCharset("utf-16le", CharsetSourceBOM)
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if label := params["charset"]; label != "" {
+			if detected := lookupCharset(label, CharsetSourceContentType); detected.Encoding != nil {
+				return detected
+			}
+		}
+	}
+
+	prescan := body
+	if len(prescan) > charsetPrescanLimit {
+		prescan = prescan[:charsetPrescanLimit]
+	}
+	if match := xmlEncoding.FindSubmatch(prescan); match != nil {
+		if detected := lookupDeclaredCharset(string(match[1]), CharsetSourceXML); detected.Encoding != nil {
+			return detected
+		}
+	}
+	if match := metaCharset.FindSubmatch(prescan); match != nil {
+		if detected := lookupDeclaredCharset(string(match[1]), CharsetSourceMeta); detected.Encoding != nil {
+			return detected
+		}
+	}
+
+	return sniffCharset(body)
+}
+
+// ToUTF8 transcodes body from the detected charset to UTF-8, dropping any byte order mark
+func ToUTF8(body []byte, detected DetectedCharset) ([]byte, error) {
+	if detected.Encoding == nil || detected.Name == "utf-8" {
+		return bytes.TrimPrefix(body, []byte{0xEF, 0xBB, 0xBF}), nil
+	}
+
+	decoded, err := io.ReadAll(transform.NewReader(bytes.NewReader(body), detected.Encoding.NewDecoder()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s content: %w", detected.Name, err)
+	}
+	return bytes.TrimPrefix(decoded, []byte{0xEF, 0xBB, 0xBF}), nil
+}
+
+// lookupCharset resolves a charset label using the WHATWG encoding names
+func lookupCharset(label, source string) DetectedCharset {
+	enc, name := charset.Lookup(strings.TrimSpace(label))
+	if enc == nil {
+		return DetectedCharset{}
+	}
+	return DetectedCharset{Encoding: enc, Name: name, Source: source}
+}
+
+// lookupDeclaredCharset resolves a charset declared inside the document. A document cannot be
+// UTF-16 if its declaration was readable as ASCII, so such declarations mean UTF-8.
+func lookupDeclaredCharset(label, source string) DetectedCharset {
+	detected := lookupCharset(label, source)
+	if strings.HasPrefix(detected.Name, "utf-16") {
+		return lookupCharset("utf-8", source)
+	}
+	return detected
+}
+
+// sniffCharset guesses the encoding of undeclared content: valid UTF-8 wins, otherwise the legacy
+// CJK encoding that decodes without errors into the most CJK characters, otherwise windows-1252
+func sniffCharset(body []byte) DetectedCharset {
+	if utf8.Valid(trimPartialRune(body)) {
+		return lookupCharset("utf-8", CharsetSourceSniffed)
+	}
+
+	sample := body
+	if len(sample) > 64*1024 {
+		sample = sample[:64*1024]
+	}
+
+	best := DetectedCharset{}
+	bestScore := 0.0
+	for _, label := range sniffCandidates {
+		candidate := lookupCharset(label, CharsetSourceSniffed)
+		if candidate.Encoding == nil {
+			continue
+		}
+		if score := cjkScore(sample, candidate.Encoding); score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	if best.Encoding != nil {
+		return best
+	}
+
+	return DetectedCharset{Encoding: charmap.Windows1252, Name: "windows-1252", Source: CharsetSourceDefault}
+}
+
+// cjkScore decodes sample and returns the share of non-ASCII runes that are CJK characters,
+// or 0 if decoding produced replacement characters
+func cjkScore(sample []byte, enc encoding.Encoding) float64 {
+	decoded, err := enc.NewDecoder().Bytes(sample)
+	if err != nil {
+		return 0
+	}
+
+	nonASCII, cjk, invalid := 0, 0, 0
+	for _, r := range string(decoded) {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		nonASCII++
+		switch {
+		case r == utf8.RuneError:
+			invalid++
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul),
+			r >= 0x3000 && r <= 0x303F, r >= 0xFF00 && r <= 0xFFEF:
+			cjk++
+		}
+	}
+
+	// Tolerate a truncated trailing sequence but nothing more
+	if nonASCII == 0 || invalid > 1 {
+		return 0
+	}
+	return float64(cjk) / float64(nonASCII)
+}
+
+// trimPartialRune removes an incomplete UTF-8 sequence at the end of b
+func trimPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i > len(b)-utf8.UTFMax; i-- {
+		if b[i] < utf8.RuneSelf {
+			break
+		}
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
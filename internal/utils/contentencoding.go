@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// SupportedEncodings lists the content codings NewDecodingReader can decode, in preference order
+var SupportedEncodings = []string{"gzip", "deflate", "br", "zstd"}
+
+// AcceptEncoding is the Accept-Encoding header value advertising exactly the supported codings
+var AcceptEncoding = strings.Join(SupportedEncodings, ", ")
+
+// NewDecodingReader wraps r in decoders for a Content-Encoding header value such as "deflate, gzip",
+// undoing the codings in reverse order. A gzip stream without a declared coding is detected by its
+// magic bytes. Closing the returned reader releases the decoders but not r.
+func NewDecodingReader(r io.Reader, contentEncoding string) (io.ReadCloser, error) {
+	var codings []string
+	for _, coding := range strings.Split(contentEncoding, ",") {
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "" && coding != "identity" {
+			codings = append(codings, coding)
+		}
+	}
+
+	buffered := bufio.NewReader(r)
+	if len(codings) == 0 {
+		if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+			codings = []string{"gzip"}
+		}
+	}
+
+	var reader io.Reader = buffered
+	var closers []io.Closer
+	for i := len(codings) - 1; i >= 0; i-- {
+		decoder, err := newDecoder(reader, codings[i])
+		if err != nil {
+			closeAll(closers)
+			return nil, err
+		}
+		closers = append(closers, decoder)
+		reader = decoder
+	}
+
+	return &decodedBody{Reader: reader, closers: closers}, nil
+}
+
+// newDecoder returns a decoder for a single content coding
+func newDecoder(r io.Reader, coding string) (io.ReadCloser, error) {
+	switch coding {
+	case "gzip", "x-gzip":
+		gzipReader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		return gzipReader, nil
+	case "deflate":
+		return newDeflateReader(r)
+	case "br":
+		return io.NopCloser(brotli.NewReader(r)), nil
+	case "zstd":
+		zstdReader, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd reader: %w", err)
+		}
+		return zstdReader.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", coding)
+	}
+}
+
+// newDeflateReader decodes "deflate" bodies, which servers send either zlib-wrapped as the
+// spec requires or as raw DEFLATE data
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	buffered, ok := r.(*bufio.Reader)
+	if !ok {
+		buffered = bufio.NewReader(r)
+	}
+
+	header, _ := buffered.Peek(2)
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		zlibReader, err := zlib.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zlib reader: %w", err)
+		}
+		return zlibReader, nil
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// decodedBody is a decoded stream that releases its decoders on Close
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close releases the decoders
+func (d *decodedBody) Close() error {
+	closeAll(d.closers)
+	return nil
+}
+
+// closeAll closes every closer, ignoring errors
+func closeAll(closers []io.Closer) {
+	for _, closer := range closers {
+		closer.Close()
+	}
+}
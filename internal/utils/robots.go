@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRobotsSize is the number of bytes of a robots.txt file that crawlers must parse at least
+const MaxRobotsSize = 500 << 10
+
+// ErrDisallowedByRobots is matched by errors.Is for every RobotsDisallowedError
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// RobotsDisallowedError reports a URL that the site's robots.txt disallows for our product token
+type RobotsDisallowedError struct {
+	URL   string
+	Agent string
+}
+
+// Error implements the error interface
+func (e *RobotsDisallowedError) Error() string {
+	return fmt.Sprintf("robots.txt disallows fetching %s for user agent %q", e.URL, e.Agent)
+}
+
+// Is makes errors.Is(err, ErrDisallowedByRobots) match
+func (e *RobotsDisallowedError) Is(target error) bool {
+	return target == ErrDisallowedByRobots
+}
+
+// Robots is a parsed robots.txt file following RFC 9309, with the common Crawl-delay extension
+type Robots struct {
+	groups []*robotsGroup
+	// Sitemaps lists the Sitemap: lines as written
+	Sitemaps []string
+}
+
+// robotsGroup holds the rules that apply to a set of user agents
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsRule is a single Allow or Disallow line
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// NewRobotsAllowAll returns rules that permit every path, used for a missing robots.txt
+func NewRobotsAllowAll() *Robots {
+	return &Robots{}
+}
+
+// ParseRobots parses a robots.txt file. Lines it does not understand are ignored, as are rules
+// that appear before the first User-agent line.
+func ParseRobots(body []byte) *Robots {
+	robots := &Robots{}
+	var group *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64<<10), MaxRobotsSize)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent", "useragent", "user agent":
+			// Consecutive User-agent lines share one group
+			if !inAgents {
+				group = &robotsGroup{}
+				robots.groups = append(robots.groups, group)
+			}
+			group.agents = append(group.agents, robotsProductToken(value))
+			inAgents = true
+			continue
+		case "sitemap":
+			if value != "" {
+				robots.Sitemaps = append(robots.Sitemaps, value)
+			}
+			continue
+		}
+
+		inAgents = false
+		if group == nil {
+			continue
+		}
+		switch key {
+		case "allow", "disallow":
+			// An empty Disallow allows everything and needs no rule
+			if value != "" {
+				group.rules = append(group.rules, robotsRule{allow: key == "allow", pattern: escapeRobotsPattern(value)})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 && group.crawlDelay == 0 {
+				group.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	return robots
+}
+
+// Allowed reports whether the path (with its query) may be fetched by agent. The longest
+// matching rule decides and Allow wins a tie; robots.txt itself is always allowed.
+func (r *Robots) Allowed(agent, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	allowed, longest := true, -1
+	for _, group := range r.match(agent) {
+		for _, rule := range group.rules {
+			if !robotsPatternMatch(rule.pattern, path) {
+				continue
+			}
+			if length := len(rule.pattern); length > longest || length == longest && rule.allow {
+				allowed, longest = rule.allow, length
+			}
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay returns the delay between requests that the site asks agent to keep, or 0
+func (r *Robots) CrawlDelay(agent string) time.Duration {
+	for _, group := range r.match(agent) {
+		if group.crawlDelay > 0 {
+			return group.crawlDelay
+		}
+	}
+	return 0
+}
+
+// match returns the groups naming agent, or else the groups for *
+func (r *Robots) match(agent string) []*robotsGroup {
+	agent = strings.ToLower(agent)
+	var named, wildcard []*robotsGroup
+	for _, group := range r.groups {
+		for _, name := range group.agents {
+			if name == agent {
+				named = append(named, group)
+				break
+			}
+			if name == "*" {
+				wildcard = append(wildcard, group)
+				break
+			}
+		}
+	}
+	if len(named) > 0 {
+		return named
+	}
+	return wildcard
+}
+
+// robotsProductToken reduces a User-agent value such as "ExampleBot/2.1" to its product token
+func robotsProductToken(value string) string {
+	value = strings.ToLower(value)
+	if i := strings.IndexAny(value, "/ "); i > 0 {
+		value = value[:i]
+	}
+	return value
+}
+
+// escapeRobotsPattern percent-encodes the characters of a pattern that appear encoded in URL
+// paths, so that rules written with raw UTF-8 match the escaped request path
+func escapeRobotsPattern(pattern string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if c := pattern[i]; c >= 0x80 || c == ' ' {
+			escaped.WriteString(fmt.Sprintf("%%%02X", c))
+		} else {
+			escaped.WriteByte(c)
+		}
+	}
+	return escaped.String()
+}
+
+// robotsPatternMatch matches a path against a rule pattern, where * matches any sequence of
+// characters and a trailing $ anchors the pattern at the end of the path
+func robotsPatternMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	rest, ok := strings.CutPrefix(path, parts[0])
+	if !ok {
+		return false
+	}
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRobotsPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/", true},
+		{"/", "/anything", true},
+		{"/fish", "/fish", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/fish/salmon.html", true},
+		{"/fish", "/Fish.asp", false},
+		{"/fish", "/catfish", false},
+		{"/fish*", "/fishheads/yummy.html", true},
+		{"/fish/", "/fish", false},
+		{"/fish/", "/fish/?id=anything", true},
+		{"/*.php", "/index.php", true},
+		{"/*.php", "/folder/filename.php?parameters", true},
+		{"/*.php", "/windows.PHP", false},
+		{"/*.php$", "/filename.php", true},
+		{"/*.php$", "/filename.php?parameters", false},
+		{"/*.php$", "/filename.php/", false},
+		{"/fish*.php", "/fishheads/catfish.php?parameters", true},
+		{"/fish*.php", "/Fish.PHP", false},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+		{"/a*b*c$", "/abcabc", true},
+		{"/a*b*c$", "/abcab", false},
+		{"/$", "/", true},
+		{"/$", "/page", false},
+		{"*", "/anything", true},
+		{"/%E6%97%A5", "/%E6%97%A5%E6%9C%AC", true},
+	}
+	for _, tt := range tests {
+		if got := robotsPatternMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("robotsPatternMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	robots := ParseRobots([]byte(`
+# Comments and unknown lines are ignored
+Disallow: /before-any-group
+
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /日本
+Crawl-delay: 2
+
+User-agent: ExampleBot/2.1
+User-agent: otherbot
+Disallow: /
+Allow: /open/
+Allow: /$
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+`))
+
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		{"somebot", "/", true},
+		{"somebot", "/before-any-group", true},
+		{"somebot", "/private/", false},
+		{"somebot", "/private/secret", false},
+		{"somebot", "/private/public/page", true},
+		{"somebot", "/docs/manual.pdf", false},
+		{"somebot", "/docs/manual.pdf?download=1", true},
+		{"somebot", "/%E6%97%A5%E6%9C%AC/page", false},
+		{"somebot", "/robots.txt", true},
+		{"ExampleBot", "/", true},
+		{"examplebot", "/page", false},
+		{"examplebot", "/open/page", true},
+		{"examplebot", "/private/public", false},
+		{"OtherBot", "/open/", true},
+		{"examplebot", "/robots.txt", true},
+	}
+	for _, tt := range tests {
+		if got := robots.Allowed(tt.agent, tt.path); got != tt.want {
+			t.Errorf("Allowed(%q, %q) = %v, want %v", tt.agent, tt.path, got, tt.want)
+		}
+	}
+
+	if got := robots.CrawlDelay("somebot"); got != 2*time.Second {
+		t.Errorf("CrawlDelay(somebot) = %v, want 2s", got)
+	}
+	if got := robots.CrawlDelay("examplebot"); got != 500*time.Millisecond {
+		t.Errorf("CrawlDelay(examplebot) = %v, want 500ms", got)
+	}
+	if len(robots.Sitemaps) != 1 || robots.Sitemaps[0] != "https://example.com/sitemap.xml" {
+		t.Errorf("Sitemaps = %v", robots.Sitemaps)
+	}
+}
+
+func TestRobotsAllowedTieAndAllowAll(t *testing.T) {
+	robots := ParseRobots([]byte("User-agent: *\nDisallow: /page\nAllow: /page\nDisallow:\n"))
+	if !robots.Allowed("bot", "/page") {
+		t.Errorf("Allowed(/page) = false, want Allow to win a tie of equal length")
+	}
+	if !NewRobotsAllowAll().Allowed("bot", "/anything") {
+		t.Errorf("NewRobotsAllowAll().Allowed = false, want true")
+	}
+	if !ParseRobots(nil).Allowed("bot", "") {
+		t.Errorf("empty robots.txt disallows /, want allowed")
+	}
+}
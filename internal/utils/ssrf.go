@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// ErrBlockedAddress is matched by errors.Is for every BlockedAddressError
+var ErrBlockedAddress = errors.New("blocked address")
+
+// BlockedAddressError reports a connection refused because the destination resolves to a
+// private, loopback, link-local, multicast, reserved or explicitly blocked address
+type BlockedAddressError struct {
+	Host   string
+	IP     netip.Addr
+	Reason string
+}
+
+// Error implements the error interface
+func (e *BlockedAddressError) Error() string {
+	if e.Host == e.IP.String() {
+		return fmt.Sprintf("refusing to connect to %s: %s address", e.IP, e.Reason)
+	}
+	return fmt.Sprintf("refusing to connect to %s (%s): %s address", e.Host, e.IP, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrBlockedAddress) match
+func (e *BlockedAddressError) Is(target error) bool {
+	return target == ErrBlockedAddress
+}
+
+// reservedPrefixes are special-purpose ranges not covered by the netip classification methods
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // shared address space, also used for cloud metadata
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, which reaches any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// Ranges of IPv6 addresses that carry an IPv4 address
+var (
+	ipv4CompatiblePrefix = netip.MustParsePrefix("::/96")
+	sixToFourPrefix      = netip.MustParsePrefix("2002::/16")
+	teredoPrefix         = netip.MustParsePrefix("2001::/32")
+)
+
+// AddressGuard dials outgoing connections only to public addresses. The host name is resolved
+// once and the connection is made to the checked IP, so a DNS answer that changes between the
+// check and the connection (DNS rebinding) cannot redirect it to an internal address.
+type AddressGuard struct {
+	blocked      []netip.Prefix
+	allowed      []netip.Prefix
+	allowedHosts []string
+	resolver     *net.Resolver
+	dialer       *net.Dialer
+}
+
+// NewAddressGuard creates a guard that additionally blocks blockedCIDRs and always permits
+// allowedCIDRs and allowedHosts. Host patterns match exactly or, as *.example.com, any subdomain.
+func NewAddressGuard(blockedCIDRs, allowedCIDRs, allowedHosts []string) (*AddressGuard, error) {
+	blocked, err := ParsePrefixes(blockedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := ParsePrefixes(allowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	guard := &AddressGuard{
+		blocked:  blocked,
+		allowed:  allowed,
+		resolver: net.DefaultResolver,
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+	}
+	for _, host := range allowedHosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			guard.allowedHosts = append(guard.allowedHosts, host)
+		}
+	}
+	return guard, nil
+}
+
+// ParsePrefixes parses CIDR ranges; a bare IP address is treated as a single-address range
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %q", value)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// AllowHost adds a host to the allowlist, e.g. the configured HTTP proxy
+func (g *AddressGuard) AllowHost(host string) {
+	if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+		g.allowedHosts = append(g.allowedHosts, host)
+	}
+}
+
+// DialContext resolves the host of address, rejects blocked addresses and connects to the
+// first reachable permitted IP. It has the signature of http.Transport.DialContext.
+func (g *AddressGuard) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if g.HostAllowed(host) {
+		return g.dialer.DialContext(ctx, network, address)
+	}
+
+	ips, err := g.Resolve(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := g.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// Resolve looks up a host and returns its addresses, or a BlockedAddressError if any of them
+// is not permitted. A host with a mix of public and internal addresses is rejected as a whole.
+func (g *AddressGuard) Resolve(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	lookupNetwork := "ip"
+	switch network {
+	case "tcp4", "udp4":
+		lookupNetwork = "ip4"
+	case "tcp6", "udp6":
+		lookupNetwork = "ip6"
+	}
+
+	ips, err := g.resolver.LookupNetIP(ctx, lookupNetwork, strings.TrimSuffix(host, "."))
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	for i, ip := range ips {
+		ip = ip.Unmap()
+		if reason := g.blockReason(ip); reason != "" {
+			return nil, &BlockedAddressError{Host: host, IP: ip, Reason: reason}
+		}
+		ips[i] = ip
+	}
+	return ips, nil
+}
+
+// HostAllowed reports whether a host name is on the allowlist
+func (g *AddressGuard) HostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range g.allowedHosts {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// blockReason classifies a blocked IP, or returns "" for a permitted one
+func (g *AddressGuard) blockReason(ip netip.Addr) string {
+	for _, prefix := range g.allowed {
+		if prefix.Contains(ip) {
+			return ""
+		}
+	}
+	for _, prefix := range g.blocked {
+		if prefix.Contains(ip) {
+			return "configured blocked"
+		}
+	}
+
+	switch {
+	case ip.IsLoopback():
+		return "loopback"
+	case ip.IsPrivate():
+		return "private"
+	case ip.IsLinkLocalUnicast():
+		return "link-local"
+	case ip.IsMulticast():
+		return "multicast"
+	case ip.IsUnspecified():
+		return "unspecified"
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return "reserved"
+		}
+	}
+
+	// Tunneling addresses are judged by the IPv4 address they lead to
+	if embedded, kind, ok := embeddedIPv4(ip); ok {
+		if reason := g.blockReason(embedded); reason != "" {
+			return fmt.Sprintf("%s IPv4 in %s", reason, kind)
+		}
+	}
+	return ""
+}
+
+// embeddedIPv4 extracts the IPv4 address carried by an IPv4-compatible, 6to4 or Teredo address
+func embeddedIPv4(ip netip.Addr) (netip.Addr, string, bool) {
+	if !ip.Is6() {
+		return netip.Addr{}, "", false
+	}
+	b := ip.As16()
+	switch {
+	case ipv4CompatiblePrefix.Contains(ip):
+		return netip.AddrFrom4([4]byte(b[12:16])), "IPv4-compatible", true
+	case sixToFourPrefix.Contains(ip):
+		return netip.AddrFrom4([4]byte(b[2:6])), "6to4", true
+	case teredoPrefix.Contains(ip):
+		// The Teredo client address is stored with its bits inverted
+		return netip.AddrFrom4([4]byte{b[12] ^ 0xff, b[13] ^ 0xff, b[14] ^ 0xff, b[15] ^ 0xff}), "Teredo", true
+	}
+	return netip.Addr{}, "", false
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestBlockReason(t *testing.T) {
+	guard, err := NewAddressGuard([]string{"203.0.113.0/24"}, []string{"10.1.0.0/16", "2001:db8::1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"93.184.216.34", ""},
+		{"2606:4700::1111", ""},
+		{"127.0.0.1", "loopback"},
+		{"::1", "loopback"},
+		{"10.0.0.1", "private"},
+		{"192.168.1.1", "private"},
+		{"fd00::1", "private"},
+		{"169.254.169.254", "link-local"},
+		{"fe80::1", "link-local"},
+		{"224.0.0.1", "multicast"},
+		{"ff02::1", "multicast"},
+		{"0.0.0.0", "unspecified"},
+		{"::", "unspecified"},
+		{"0.1.2.3", "reserved"},
+		{"100.100.100.200", "reserved"},
+		{"198.18.0.1", "reserved"},
+		{"255.255.255.255", "reserved"},
+		{"64:ff9b::5db8:d822", "reserved"},
+		{"64:ff9b:1::1", "reserved"},
+		{"::7f00:1", "loopback IPv4 in IPv4-compatible"},
+		{"::5db8:d822", ""},
+		{"2002:a9fe:a9fe::1", "link-local IPv4 in 6to4"},
+		{"2002:5db8:d822::1", ""},
+		{"2001:0:4136:e378:8000:63bf:80ff:fffe", "loopback IPv4 in Teredo"},
+		{"2001:0:4136:e378:8000:63bf:a247:27dd", ""},
+		{"2002:cb00:7101::1", "configured blocked IPv4 in 6to4"},
+		{"203.0.113.7", "configured blocked"},
+		{"10.1.2.3", ""},
+		{"2002:0a01:0203::1", ""},
+		{"2001:db8::1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := guard.blockReason(netip.MustParseAddr(tt.ip)); got != tt.want {
+				t.Errorf("blockReason(%s) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	guard, err := NewAddressGuard(nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		network string
+		host    string
+		want    string
+		blocked bool
+	}{
+		{name: "public IPv4", network: "tcp", host: "93.184.216.34", want: "93.184.216.34"},
+		{name: "public IPv6", network: "tcp", host: "2606:4700::1111", want: "2606:4700::1111"},
+		{name: "IPv4-mapped IPv6 is unmapped", network: "tcp", host: "::ffff:93.184.216.34", want: "93.184.216.34"},
+		{name: "loopback", network: "tcp", host: "127.0.0.1", blocked: true},
+		{name: "IPv4-mapped loopback", network: "tcp", host: "::ffff:127.0.0.1", blocked: true},
+		{name: "cloud metadata", network: "tcp4", host: "169.254.169.254", blocked: true},
+		{name: "NAT64", network: "tcp6", host: "64:ff9b::a9fe:a9fe", blocked: true},
+		{name: "6to4 private", network: "tcp6", host: "2002:c0a8:0101::1", blocked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ips, err := guard.Resolve(context.Background(), tt.network, tt.host)
+			if tt.blocked {
+				var blocked *BlockedAddressError
+				if !errors.As(err, &blocked) || !errors.Is(err, ErrBlockedAddress) {
+					t.Fatalf("Resolve(%s) = %v, %v; want a BlockedAddressError", tt.host, ips, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%s) failed: %v", tt.host, err)
+			}
+			if len(ips) != 1 || ips[0].String() != tt.want {
+				t.Errorf("Resolve(%s) = %v, want [%s]", tt.host, ips, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,18 @@
 package types
 
+import "time"
+
 // WebSearchRequest represents the request structure for BigModel Web Search API
 type WebSearchRequest struct {
-	SearchQuery  string `json:"search_query"`
-	SearchEngine string `json:"search_engine"`
-	SearchIntent bool   `json:"search_intent"`
+	SearchQuery         string `json:"search_query"`
+	SearchEngine        string `json:"search_engine"`
+	SearchIntent        bool   `json:"search_intent"`
+	Count               int    `json:"count,omitempty"`
+	SearchDomainFilter  string `json:"search_domain_filter,omitempty"`
+	SearchRecencyFilter string `json:"search_recency_filter,omitempty"`
+	ContentSize         string `json:"content_size,omitempty"`
+	RequestID           string `json:"request_id,omitempty"`
+	UserID              string `json:"user_id,omitempty"`
 }
 
 // WebSearchResponse represents the response structure from BigModel Web Search API
@@ -16,6 +24,23 @@ type WebSearchResponse struct {
 	SearchResult []SearchResult `json:"search_result"`
 }
 
+// SearchResponse represents a provider-neutral search response
+type SearchResponse struct {
+	Provider     string         `json:"provider"`
+	RequestID    string         `json:"request_id"`
+	SearchIntent []SearchIntent `json:"search_intent"`
+	SearchResult []SearchResult `json:"search_result"`
+	// Errors maps provider names to the error they returned during a fan-out search
+	Errors map[string]string `json:"errors,omitempty"`
+	// FilteredCount is the number of results removed by domain filters
+	FilteredCount int `json:"filtered_count,omitempty"`
+	// DeniedCount is the number of results removed by the URL policy, including results whose
+	// link cannot be parsed
+	DeniedCount int `json:"denied_count,omitempty"`
+	// Cached is true when the response was served from the search cache
+	Cached bool `json:"cached,omitempty"`
+}
+
 // SearchIntent represents search intent information
 type SearchIntent struct {
 	Query    string `json:"query"`
@@ -32,11 +57,21 @@ type SearchResult struct {
 	Icon        string `json:"icon"`
 	Refer       string `json:"refer"`
 	PublishDate string `json:"publish_date"`
+	// Engines lists the providers that returned this result when results are fused
+	Engines []string `json:"engines,omitempty"`
+	// Score is the reciprocal-rank fusion score of a fused result
+	Score float64 `json:"score,omitempty"`
+	// RequiresConfirmation is set when the URL policy only allows fetching the link once the user confirms
+	RequiresConfirmation bool `json:"requires_confirmation,omitempty"`
 }
 
 // WebPageContent represents the content of a fetched web page
 type WebPageContent struct {
-	URL         string            `json:"url"`
+	URL string `json:"url"`
+	// FinalURL is the address the page was served from after following redirects, and
+	// Redirects the hops that led there
+	FinalURL    string            `json:"final_url"`
+	Redirects   []RedirectHop     `json:"redirects,omitempty"`
 	Title       string            `json:"title"`
 	Content     string            `json:"content"`
 	Description string            `json:"description"`
@@ -48,6 +83,56 @@ type WebPageContent struct {
 	Images      []string          `json:"images"`
 	StatusCode  int               `json:"status_code"`
 	ContentType string            `json:"content_type"`
+	CacheStatus string            `json:"cache_status,omitempty"`
+	Format      string            `json:"format"`
+	// Charset is the detected character encoding and CharsetSource how it was determined
+	// (bom, content-type, meta, sniffed or default)
+	Charset       string `json:"charset,omitempty"`
+	CharsetSource string `json:"charset_source,omitempty"`
+	// CreatedAt, PageCount and Pages describe PDF documents; Pages holds the text of each page
+	CreatedAt string   `json:"created_at,omitempty"`
+	PageCount int      `json:"page_count,omitempty"`
+	Pages     []string `json:"pages,omitempty"`
+	// Truncated is true when the body exceeded the size limit and only its beginning was parsed
+	Truncated bool `json:"truncated,omitempty"`
+	// StructuredData holds the machine-readable metadata of HTML pages when requested
+	StructuredData *StructuredData `json:"structured_data,omitempty"`
+	// Matches holds the values extracted by a CSS selector or XPath query, and MatchCount
+	// the number of matching nodes before only the first was kept
+	Matches    []string `json:"matches,omitempty"`
+	MatchCount int      `json:"match_count,omitempty"`
+	// TotalLength is the length of the full extracted content in characters
+	TotalLength int `json:"total_length"`
+	// StartIndex is the character offset of Content within the full content
+	StartIndex int `json:"start_index"`
+	// NextIndex is the offset of the next chunk, or 0 when Content reaches the end
+	NextIndex int `json:"next_index,omitempty"`
+}
+
+// StructuredData represents the machine-readable metadata embedded in an HTML page
+type StructuredData struct {
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	SiteName     string `json:"site_name,omitempty"`
+	// PublishedTime and ModifiedTime are normalized to RFC 3339 when they can be parsed
+	PublishedTime string `json:"published_time,omitempty"`
+	ModifiedTime  string `json:"modified_time,omitempty"`
+	// OpenGraph and TwitterCard map property names (og:title, twitter:card) to their first value
+	OpenGraph   map[string]string `json:"open_graph,omitempty"`
+	TwitterCard map[string]string `json:"twitter_card,omitempty"`
+	// JSONLD holds the decoded value of every JSON-LD script block
+	JSONLD    []any            `json:"json_ld,omitempty"`
+	Microdata []StructuredItem `json:"microdata,omitempty"`
+	RDFa      []StructuredItem `json:"rdfa,omitempty"`
+	// Errors lists blocks that could not be parsed
+	Errors []string `json:"errors,omitempty"`
+}
+
+// StructuredItem represents a Microdata or RDFa item
+type StructuredItem struct {
+	Type []string `json:"type,omitempty"`
+	ID   string   `json:"id,omitempty"`
+	// Properties maps property names to their values: strings or nested *StructuredItem
+	Properties map[string][]any `json:"properties"`
 }
 
 // WebFetchOptions represents options for web fetching
@@ -56,11 +141,137 @@ type WebFetchOptions struct {
 	IncludeLinks  bool
 	IncludeImages bool
 	UserAgent     string
+	// Format is text (default), markdown or html
+	Format string
+	// LinkStyle is inline (default) or reference for Markdown output
+	LinkStyle string
+	// StartIndex is the character offset of the first returned character
+	StartIndex int
+	// MaxLength limits the returned content; 0 uses WebFetch.MaxContentSize
+	MaxLength int
+	// JSONPath selects values from JSON responses, e.g. $.items[0].name
+	JSONPath string
+	// IncludeStructuredData extracts JSON-LD, Microdata, RDFa, OpenGraph and Twitter Card data
+	IncludeStructuredData bool
+	// Selector (CSS) or XPath replaces the main content with the matching nodes
+	Selector string
+	XPath    string
+	// Extract is text (default), html (inner HTML) or attribute, which reads Attribute
+	Extract   string
+	Attribute string
+	// AllMatches returns every match instead of only the first
+	AllMatches bool
+	// Confirm records that the user approved the requested URL, which the policy marks as
+	// requiring confirmation; redirects to other such URLs still need their own approval
+	Confirm bool
+	// MaxRedirects, AllowCrossHostRedirects and RefuseHTTPSDowngrade override the configured
+	// redirect policy when set
+	MaxRedirects            *int
+	AllowCrossHostRedirects *bool
+	RefuseHTTPSDowngrade    *bool
+}
+
+// RedirectHop is one redirect followed while fetching a page
+type RedirectHop struct {
+	// URL is the address that answered with the redirect
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
 }
 
 // WebSearchOptions represents options for web searching
 type WebSearchOptions struct {
 	Query        string
 	SearchEngine string
-	SearchIntent bool
+	// SearchEngines queries several providers concurrently and fuses their results
+	SearchEngines []string
+	SearchIntent  bool
+	// Count limits the number of results returned by the provider
+	Count int
+	// DomainFilter restricts results to a single domain
+	DomainFilter string
+	// RecencyFilter is one of day, week, month or year
+	RecencyFilter string
+	// ContentSize is medium or high and controls the length of result summaries
+	ContentSize string
+	RequestID   string
+	UserID      string
+	// IncludeDomains keeps only results whose link is on one of these domains or their subdomains
+	IncludeDomains []string
+	// ExcludeDomains drops results whose link is on one of these domains or their subdomains
+	ExcludeDomains []string
+}
+
+// FeedOptions represents options for reading an RSS, Atom or JSON feed
+type FeedOptions struct {
+	URL string
+	// Since drops entries published before this time when non-zero
+	Since time.Time
+	// Limit caps the number of returned entries
+	Limit int
+}
+
+// Feed represents a parsed feed with normalized entries
+type Feed struct {
+	// URL is the feed URL that was parsed
+	URL string `json:"url"`
+	// SourceURL is the HTML page the feed was discovered from, if any
+	SourceURL string `json:"source_url,omitempty"`
+	// DiscoveredFeeds lists every feed advertised by the source page
+	DiscoveredFeeds []string `json:"discovered_feeds,omitempty"`
+	// Format is rss, atom or json
+	Format      string      `json:"format"`
+	Title       string      `json:"title"`
+	Link        string      `json:"link"`
+	Description string      `json:"description"`
+	Entries     []FeedEntry `json:"entries"`
+	// TotalEntries is the number of entries in the feed before filtering
+	TotalEntries int `json:"total_entries"`
+}
+
+// FeedEntry represents a normalized feed entry
+type FeedEntry struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Link  string `json:"link"`
+	// Published is the publication (or last update) date in RFC 3339 format
+	Published string `json:"published"`
+	Summary   string `json:"summary"`
+	Author    string `json:"author"`
+}
+
+// SitemapOptions represents options for enumerating the URLs of a site's sitemaps
+type SitemapOptions struct {
+	// URL is a site URL, whose sitemaps are discovered, or the URL of a sitemap
+	URL string
+	// PathPrefix keeps only URLs whose path starts with this prefix
+	PathPrefix string
+	// Since drops URLs last modified before this time when non-zero
+	Since time.Time
+	// Limit caps the number of returned URLs
+	Limit int
+}
+
+// Sitemap represents the URLs collected from a site's sitemaps
+type Sitemap struct {
+	// Site is the requested URL
+	Site string `json:"site"`
+	// Sitemaps lists the sitemaps that were read, including nested ones from sitemap indexes
+	Sitemaps []string `json:"sitemaps"`
+	// Errors lists sitemaps that could not be read
+	Errors []string     `json:"errors,omitempty"`
+	URLs   []SitemapURL `json:"urls"`
+	// MatchedURLs is the number of URLs matching the filters before the limit was applied
+	MatchedURLs int `json:"matched_urls"`
+	// TotalURLs is the number of URLs found before filtering
+	TotalURLs int `json:"total_urls"`
+}
+
+// SitemapURL represents a <url> entry of a sitemap
+type SitemapURL struct {
+	Loc string `json:"loc"`
+	// LastMod is the last modification date in RFC 3339 format
+	LastMod    string `json:"lastmod,omitempty"`
+	ChangeFreq string `json:"changefreq,omitempty"`
+	Priority   string `json:"priority,omitempty"`
 }